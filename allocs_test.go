@@ -0,0 +1,70 @@
+package geoiplegacy
+
+import (
+	"net"
+	"net/netip"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountryLookupAllocs(t *testing.T) {
+	tests := []struct {
+		name    string
+		options GeoIPOptions
+	}{
+		{"MemoryCache", GeoIPOptions{MemoryCache: true}},
+		{"MMapCache", GeoIPOptions{MMapCache: true}},
+		{"IndexCache", GeoIPOptions{IndexCache: true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.options.MMapCache && runtime.GOOS != "linux" {
+				t.Skip("memory mapped databases are only supported on Linux")
+			}
+			v4 := newTestDBBuilder(CountryEdition, false)
+			v4.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+			v4DB := v4.openWithOptions(t, &tc.options)
+
+			v6 := newTestDBBuilder(CountryEditionV6, true)
+			v6.insert("2801::/16", uint(testCountryIndex(t, "UY")))
+			v6DB := v6.openWithOptions(t, &tc.options)
+
+			ip4 := net.ParseIP("8.8.8.8")
+			ip6 := net.ParseIP("2801::1")
+			addr6 := netip.MustParseAddr("2801::1")
+			lookups := map[string]func() error{
+				"GetCountryByIP IPv4": func() error {
+					_, err := v4DB.GetCountryByIP(ip4)
+					return err
+				},
+				"GetCountryByIP IPv6": func() error {
+					_, err := v6DB.GetCountryByIP(ip6)
+					return err
+				},
+				"GetCountryAndNetworkByIP": func() error {
+					_, _, err := v4DB.GetCountryAndNetworkByIP(ip4)
+					return err
+				},
+				"GetCountryByNetIP": func() error {
+					_, err := v6DB.GetCountryByNetIP(addr6)
+					return err
+				},
+				"ParseAndLookup": func() error {
+					_, err := v4DB.ParseAndLookup("8.8.8.8")
+					return err
+				},
+			}
+			for name, lookup := range lookups {
+				if !assert.NoError(t, lookup(), name) {
+					return
+				}
+				allocs := testing.AllocsPerRun(100, func() {
+					lookup()
+				})
+				assert.Zero(t, allocs, "%s allocated", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package geoiplegacy
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ASNResult is the result of scanning an ASNum Edition database for the
+// autonomous system of a network address
+type ASNResult struct {
+	Number uint32
+	Org    string
+}
+
+// parseASN parses an ASNum Edition record in the form "AS15169 Google LLC"
+func parseASN(record string) (*ASNResult, error) {
+	if !strings.HasPrefix(record, "AS") {
+		return nil, ErrInvalidRecord
+	}
+	numStr, org, _ := strings.Cut(record[2:], " ")
+	num, err := strconv.ParseUint(numStr, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidRecord
+	}
+	return &ASNResult{
+		Number: uint32(num),
+		Org:    org,
+	}, nil
+}
+
+// GetASNByIP scans an ASNum Edition database for the autonomous system of the given
+// IP address. If the address is not in the database, ErrRecordNotFound is returned
+func (db *DB) GetASNByIP(ip net.IP) (*ASNResult, error) {
+	record, err := db.getNameByIP(ip, ASNEdition, ASNEditionV6)
+	if err != nil {
+		return nil, err
+	}
+	return parseASN(record)
+}
+
+// GetASNByAddr scans an ASNum Edition database for the given IP address or domain.
+// If a domain is passed to it, it tries to resolve it to an IP, then looks that up.
+func (db *DB) GetASNByAddr(addr string) (*ASNResult, error) {
+	record, err := db.getNameByAddr(addr, ASNEdition, ASNEditionV6)
+	if err != nil {
+		return nil, err
+	}
+	return parseASN(record)
+}
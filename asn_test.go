@@ -0,0 +1,62 @@
+package geoiplegacy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestASNByIPv4(t *testing.T) {
+	b := newTestDBBuilder(ASNEdition, false)
+	b.insert("8.8.8.0/24", b.addString("AS15169 Google LLC"))
+	b.insert("81.91.0.0/16", b.addString("AS3320"))
+	b.insert("10.0.0.0/8", b.addString("invalid"))
+	db := b.open(t)
+
+	asn, err := db.GetASNByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualValues(t, 15169, asn.Number)
+	assert.Equal(t, "Google LLC", asn.Org)
+
+	asn, err = db.GetASNByAddr("81.91.170.12")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualValues(t, 3320, asn.Number)
+	assert.Equal(t, "", asn.Org)
+
+	org, err := db.GetOrgByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "AS15169 Google LLC", org)
+
+	_, err = db.GetASNByAddr("10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidRecord)
+
+	_, err = db.GetASNByAddr("127.0.0.1")
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestASNByIPv6(t *testing.T) {
+	b := newTestDBBuilder(ASNEditionV6, true)
+	b.insert("2001:4860::/32", b.addString("AS15169 Google LLC"))
+	db := b.open(t)
+
+	asn, err := db.GetASNByAddr("2001:4860:4860::8888")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualValues(t, 15169, asn.Number)
+	assert.Equal(t, "Google LLC", asn.Org)
+}
+
+func TestASNInvalidDBType(t *testing.T) {
+	b := newTestDBBuilder(ISPEdition, false)
+	db := b.open(t)
+
+	_, err := db.GetASNByAddr("8.8.8.8")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
@@ -0,0 +1,108 @@
+package geoiplegacy
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+)
+
+var ErrOutputTooShort = errors.New("output slice is shorter than the address slice")
+
+// sortedAddrIndexes returns the indexes of addrs for which include returns true,
+// sorted by address so that neighbouring addresses are looked up together
+func sortedAddrIndexes(addrs []netip.Addr, include func(netip.Addr) bool) []int {
+	indexes := make([]int, 0, len(addrs))
+	for i, addr := range addrs {
+		if include(addr) {
+			indexes = append(indexes, i)
+		}
+	}
+	slices.SortFunc(indexes, func(a, b int) int {
+		return addrs[a].Unmap().Compare(addrs[b].Unmap())
+	})
+	return indexes
+}
+
+// lookupCountries looks up the countries of the addresses at the given indexes,
+// which must be sorted, storing them at the same indexes of out. Addresses in
+// the network of the previous address reuse its country instead of traversing
+// the tree again. The caller must have called beginLookup
+func (db *DB) lookupCountries(addrs []netip.Addr, out []CountryResult, indexes []int) error {
+	var network netip.Prefix
+	var country *CountryResult
+	for _, i := range indexes {
+		addr := addrs[i].Unmap()
+		if !addr.IsValid() {
+			return fmt.Errorf("address %d: %w", i, ErrInvalidIP)
+		}
+		if country == nil || !network.Contains(addr) {
+			ip := addr.As16()
+			var err error
+			if country, network, err = db.countryAndNetworkByIP(ip[:]); err != nil {
+				return fmt.Errorf("address %d (%s): %w", i, addr, err)
+			}
+		}
+		out[i] = *country
+	}
+	return nil
+}
+
+// LookupCountries scans the database for the country of each address in addrs
+// without resolving anything, storing it at the same index of out. It is faster
+// than looking the addresses up one at a time, especially when many of them are
+// in the same networks. If an address can't be looked up, an error including its
+// index is returned and the contents of out are undefined
+func (db *DB) LookupCountries(addrs []netip.Addr, out []CountryResult) error {
+	if len(out) < len(addrs) {
+		return ErrOutputTooShort
+	}
+	db.beginLookup()
+	defer db.endLookup()
+	indexes := sortedAddrIndexes(addrs, func(netip.Addr) bool { return true })
+	return db.lookupCountries(addrs, out, indexes)
+}
+
+// LookupCountries scans the IPv4 and IPv6 databases for the country of each
+// address in addrs, storing it at the same index of out, like DB.LookupCountries
+func (db *CombinedDB) LookupCountries(addrs []netip.Addr, out []CountryResult) error {
+	if len(out) < len(addrs) {
+		return ErrOutputTooShort
+	}
+	for i, addr := range addrs {
+		if !addr.IsValid() {
+			return fmt.Errorf("address %d: %w", i, ErrInvalidIP)
+		}
+	}
+	is4 := func(addr netip.Addr) bool {
+		return addr.Unmap().Is4()
+	}
+	v4Indexes := sortedAddrIndexes(addrs, is4)
+	v6Indexes := sortedAddrIndexes(addrs, func(addr netip.Addr) bool {
+		return !is4(addr)
+	})
+
+	for _, batch := range []struct {
+		db      *DB
+		indexes []int
+		err     error
+	}{
+		{db.v4DB, v4Indexes, ErrIPv4NotInitialized},
+		{db.v6DB, v6Indexes, ErrIPv6NotInitialized},
+	} {
+		if len(batch.indexes) == 0 {
+			continue
+		}
+		if batch.db == nil {
+			i := batch.indexes[0]
+			return fmt.Errorf("address %d (%s): %w", i, addrs[i], batch.err)
+		}
+		batch.db.beginLookup()
+		err := batch.db.lookupCountries(addrs, out, batch.indexes)
+		batch.db.endLookup()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
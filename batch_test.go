@@ -0,0 +1,134 @@
+package geoiplegacy
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testBatchDBs(t testing.TB) (*DB, *DB) {
+	v4 := newTestDBBuilder(CountryEdition, false)
+	v4.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	v4.insert("81.91.0.0/16", uint(testCountryIndex(t, "DE")))
+
+	v6 := newTestDBBuilder(CountryEditionV6, true)
+	v6.insert("2801::/16", uint(testCountryIndex(t, "UY")))
+	v6.insert("2001:4860::/32", uint(testCountryIndex(t, "US")))
+	return v4.openWithOptions(t, &GeoIPOptions{MemoryCache: true}),
+		v6.openWithOptions(t, &GeoIPOptions{IsIPv6: true, MemoryCache: true})
+}
+
+func parseAddrs(addrs ...string) []netip.Addr {
+	parsed := make([]netip.Addr, len(addrs))
+	for i, addr := range addrs {
+		parsed[i] = netip.MustParseAddr(addr)
+	}
+	return parsed
+}
+
+func TestLookupCountries(t *testing.T) {
+	v4DB, v6DB := testBatchDBs(t)
+
+	addrs := parseAddrs("8.8.8.8", "81.91.170.12", "8.8.8.9", "10.0.0.1", "::ffff:8.8.8.1", "8.8.4.4")
+	out := make([]CountryResult, len(addrs))
+	if !assert.NoError(t, v4DB.LookupCountries(addrs, out)) {
+		return
+	}
+	for i, addr := range addrs {
+		country, err := v4DB.GetCountryByNetIP(addr)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, *country, out[i], addr.String())
+	}
+	assert.Equal(t, "US", out[0].Code)
+	assert.Equal(t, "DE", out[1].Code)
+	assert.Equal(t, "--", out[3].Code)
+
+	addrs = parseAddrs("2801::1", "2001:4860:4860::8888", "2801:ffff::")
+	out = make([]CountryResult, len(addrs))
+	if !assert.NoError(t, v6DB.LookupCountries(addrs, out)) {
+		return
+	}
+	assert.Equal(t, "UY", out[0].Code)
+	assert.Equal(t, "US", out[1].Code)
+	assert.Equal(t, "UY", out[2].Code)
+}
+
+func TestLookupCountriesErrors(t *testing.T) {
+	v4DB, _ := testBatchDBs(t)
+
+	addrs := parseAddrs("8.8.8.8", "8.8.4.4")
+	err := v4DB.LookupCountries(addrs, make([]CountryResult, 1))
+	assert.ErrorIs(t, err, ErrOutputTooShort)
+
+	addrs = append(addrs, netip.Addr{})
+	err = v4DB.LookupCountries(addrs, make([]CountryResult, len(addrs)))
+	assert.ErrorIs(t, err, ErrInvalidIP)
+	assert.ErrorContains(t, err, "address 2")
+}
+
+func TestCombinedLookupCountries(t *testing.T) {
+	v4DB, v6DB := testBatchDBs(t)
+	db := &CombinedDB{v4DB: v4DB, v6DB: v6DB}
+
+	addrs := parseAddrs("2801::1", "8.8.8.8", "2001:4860:4860::8888", "81.91.170.12", "::ffff:8.8.8.1")
+	out := make([]CountryResult, len(addrs))
+	if !assert.NoError(t, db.LookupCountries(addrs, out)) {
+		return
+	}
+	codes := make([]string, len(out))
+	for i, country := range out {
+		codes[i] = country.Code
+	}
+	assert.Equal(t, []string{"UY", "US", "US", "DE", "US"}, codes)
+
+	db.v6DB = nil
+	err := db.LookupCountries(addrs, out)
+	assert.ErrorIs(t, err, ErrIPv6NotInitialized)
+
+	err = db.LookupCountries(addrs[1:2], out)
+	assert.NoError(t, err)
+}
+
+func benchmarkAddrs() []netip.Addr {
+	addrs := make([]netip.Addr, 1024)
+	for i := range addrs {
+		if i%2 == 0 {
+			addrs[i] = netip.AddrFrom4([4]byte{8, 8, 8, byte(i)})
+		} else {
+			addrs[i] = netip.AddrFrom4([4]byte{81, 91, byte(i >> 8), byte(i)})
+		}
+	}
+	return addrs
+}
+
+func BenchmarkLookupCountries(b *testing.B) {
+	db, _ := testBatchDBs(b)
+	addrs := benchmarkAddrs()
+	out := make([]CountryResult, len(addrs))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.LookupCountries(addrs, out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLookupCountriesOneByOne(b *testing.B) {
+	db, _ := testBatchDBs(b)
+	addrs := benchmarkAddrs()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, addr := range addrs {
+			if _, err := db.GetCountryByNetIP(addr); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package geoiplegacy
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testNodeChild is one of the two branches of a node in a test database tree. If
+// isNode is false, value is the leaf value relative to the first segment
+type testNodeChild struct {
+	isNode bool
+	value  uint
+}
+
+// testDBBuilder assembles synthetic legacy databases so that lookups can be
+// tested without the MaxMind databases being installed
+type testDBBuilder struct {
+	dbType       DBType
+	v6           bool
+	recordLength int
+	nodes        [][2]testNodeChild
+	records      []byte
+	info         string
+}
+
+func newTestDBBuilder(dbType DBType, v6 bool) *testDBBuilder {
+	recordLength := StandardRecordLength
+	switch dbType {
+	case OrgEdition, OrgEditionV6, DomainEdition, DomainEditionV6, ISPEdition, ISPEditionV6:
+		recordLength = OrgRecordLength
+	}
+	return &testDBBuilder{
+		dbType:       dbType,
+		v6:           v6,
+		recordLength: recordLength,
+		nodes:        make([][2]testNodeChild, 1),
+		// offset 0 of the record segment is reserved, since a pointer to it
+		// means that there is no record for the address
+		records: []byte{0},
+	}
+}
+
+// hasRecordSegment returns true if leaves point into a second segment of the
+// file rather than storing the value directly
+func (b *testDBBuilder) hasRecordSegment() bool {
+	return (&DB{Type: b.dbType}).hasContent()
+}
+
+func (b *testDBBuilder) firstSegment() uint {
+	switch b.dbType {
+	case LargeCountryEdition, LargeCountryEditionV6:
+		return LargeCountryBegin
+	case RegionEditionRev0:
+		return StateBeginRev0
+	case RegionEditionRev1:
+		return StateBeginRev1
+	}
+	if b.hasRecordSegment() {
+		return uint(len(b.nodes))
+	}
+	return CountryBegin
+}
+
+// insert sets the leaf value of every address in the prefix. Broader prefixes
+// must be inserted before the narrower prefixes they contain
+func (b *testDBBuilder) insert(prefix string, value uint) {
+	p := netip.MustParsePrefix(prefix)
+	var addr []byte
+	if b.v6 {
+		a := p.Addr().As16()
+		addr = a[:]
+	} else {
+		a := p.Addr().As4()
+		addr = a[:]
+	}
+	node := 0
+	for depth := 0; depth < p.Bits(); depth++ {
+		bit := (addr[depth/8] >> (7 - depth%8)) & 1
+		if depth == p.Bits()-1 {
+			b.nodes[node][bit] = testNodeChild{value: value}
+			break
+		}
+		child := b.nodes[node][bit]
+		if !child.isNode {
+			b.nodes = append(b.nodes, [2]testNodeChild{child, child})
+			b.nodes[node][bit] = testNodeChild{isNode: true, value: uint(len(b.nodes) - 1)}
+		}
+		node = int(b.nodes[node][bit].value)
+	}
+}
+
+// addRecord appends a record to the record segment and returns its offset
+func (b *testDBBuilder) addRecord(record []byte) uint {
+	offset := uint(len(b.records))
+	b.records = append(b.records, record...)
+	return offset
+}
+
+func (b *testDBBuilder) bytes() []byte {
+	segment := b.firstSegment()
+	var data []byte
+	for _, node := range b.nodes {
+		for _, child := range node {
+			value := child.value
+			if !child.isNode {
+				value += segment
+			}
+			for i := 0; i < b.recordLength; i++ {
+				data = append(data, byte(value>>(i*8)))
+			}
+		}
+	}
+	if b.hasRecordSegment() {
+		data = append(data, b.records...)
+	}
+	if b.info != "" {
+		data = append(data, 0, 0, 0)
+		data = append(data, b.info...)
+	}
+	data = append(data, 255, 255, 255, byte(b.dbType))
+	if b.hasRecordSegment() {
+		data = append(data, byte(segment), byte(segment>>8), byte(segment>>16))
+	}
+	return data
+}
+
+// write writes the database to a temporary file and returns its path
+func (b *testDBBuilder) write(t testing.TB) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.dat")
+	if err := os.WriteFile(path, b.bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// open writes the database to a temporary file and opens it, closing it when
+// the test finishes
+func (b *testDBBuilder) open(t testing.TB) *DB {
+	t.Helper()
+	return b.openWithOptions(t, &GeoIPOptions{IsIPv6: b.v6})
+}
+
+// openWithOptions writes the database to a temporary file and opens it with the
+// given options, closing it when the test finishes
+func (b *testDBBuilder) openWithOptions(t testing.TB, options *GeoIPOptions) *DB {
+	t.Helper()
+	db, err := OpenDB(b.write(t), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+	return db
+}
+
+// addString appends a NUL-terminated string record and returns its offset
+func (b *testDBBuilder) addString(str string) uint {
+	return b.addRecord(append([]byte(str), 0))
+}
+
+// testCountryIndex returns the index of the country code in the country tables
+func testCountryIndex(t testing.TB, code string) int {
+	t.Helper()
+	for i, c := range countryCodes {
+		if c == code {
+			return i
+		}
+	}
+	t.Fatalf("unknown country code %q", code)
+	return 0
+}
+
+// testCityRecord encodes a city record the way it is stored in City Editions
+func testCityRecord(countryID int, region, city, postal string, lat, lon float64, metroArea int) []byte {
+	record := []byte{byte(countryID)}
+	for _, str := range []string{region, city, postal} {
+		record = append(record, str...)
+		record = append(record, 0)
+	}
+	var num [4]byte
+	binary.LittleEndian.PutUint32(num[:], uint32((lat+180)*10000+0.5))
+	record = append(record, num[:3]...)
+	binary.LittleEndian.PutUint32(num[:], uint32((lon+180)*10000+0.5))
+	record = append(record, num[:3]...)
+	if metroArea > 0 {
+		binary.LittleEndian.PutUint32(num[:], uint32(metroArea))
+		record = append(record, num[:3]...)
+	}
+	return record
+}
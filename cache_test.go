@@ -0,0 +1,166 @@
+package geoiplegacy
+
+import (
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCache(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	b.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	b.insert("81.91.0.0/16", uint(testCountryIndex(t, "DE")))
+	db := b.openWithOptions(t, &GeoIPOptions{MemoryCache: true})
+
+	if !assert.Len(t, db.cache, int(db.Size)) || !assert.Equal(t, CountryEdition, db.Type) {
+		return
+	}
+
+	country, err := db.GetCountryByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+
+	country, err = db.GetCountryByAddr("81.91.170.12")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "DE", country.Code)
+}
+
+func TestMemoryCacheRecords(t *testing.T) {
+	b := newTestDBBuilder(ISPEditionV6, true)
+	b.insert("2001:4860::/32", b.addString("Google LLC"))
+	// the last record in the file is shorter than MaxOrgRecordLength
+	b.insert("2a0b:5f80::/29", b.addString("Last"))
+	b.info = "GEO-122 20240102 Build 1"
+	db := b.openWithOptions(t, &GeoIPOptions{IsIPv6: true, MemoryCache: true})
+
+	isp, err := db.GetISPByAddr("2001:4860::1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Google LLC", isp)
+
+	isp, err = db.GetISPByAddr("2a0b:5f80::1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Last", isp)
+
+	info, err := db.Info()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "GEO-122 20240102 Build 1", info.Raw)
+}
+
+func TestMMapCache(t *testing.T) {
+	b := newTestDBBuilder(CityEditionRev1, false)
+	b.insert("8.8.8.0/24", b.addRecord(testCityRecord(testCountryIndex(t, "US"),
+		"CA", "Mountain View", "94043", 37.4192, -122.0574, 807650)))
+	path := b.write(t)
+
+	db, err := OpenDB(path, &GeoIPOptions{MMapCache: true})
+	if runtime.GOOS != "linux" {
+		assert.ErrorIs(t, err, ErrMMapUnsupported)
+		return
+	}
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.True(t, db.mmapped) || !assert.Len(t, db.cache, int(db.Size)) {
+		return
+	}
+
+	city, err := db.GetCityByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Mountain View", city.City)
+	assert.Equal(t, 807, city.MetroCode)
+
+	assert.NoError(t, db.Close())
+	assert.False(t, db.mmapped)
+	assert.Nil(t, db.cache)
+}
+
+func TestIndexCache(t *testing.T) {
+	b := newTestDBBuilder(ISPEdition, false)
+	b.insert("8.8.8.0/24", b.addString("Google LLC"))
+	b.insert("81.91.160.0/20", b.addString("Deutsche Telekom AG"))
+	db := b.openWithOptions(t, &GeoIPOptions{IndexCache: true})
+
+	if !assert.Nil(t, db.cache) || !assert.Len(t, db.indexCache, len(b.nodes)*2*OrgRecordLength) {
+		return
+	}
+	assert.EqualValues(t, len(db.indexCache), db.GetIndexSize())
+
+	isp, err := db.GetISPByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Google LLC", isp)
+
+	isp, err = db.GetISPByAddr("81.91.170.12")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Deutsche Telekom AG", isp)
+}
+
+func TestIndexCacheIgnoredWithMemoryCache(t *testing.T) {
+	b := newTestDBBuilder(CityEditionRev1, false)
+	db := b.openWithOptions(t, &GeoIPOptions{IndexCache: true, MemoryCache: true})
+
+	assert.Nil(t, db.indexCache)
+	assert.Len(t, db.cache, int(db.Size))
+}
+
+func benchmarkCountryLookup(b *testing.B, options *GeoIPOptions) {
+	builder := newTestDBBuilder(CountryEdition, false)
+	builder.insert("8.8.8.0/24", uint(testCountryIndex(b, "US")))
+	db := builder.openWithOptions(b, options)
+	ip := net.ParseIP("8.8.8.8")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.GetCountryByIP(ip); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCountryLookupFile(b *testing.B) {
+	benchmarkCountryLookup(b, nil)
+}
+
+func BenchmarkCountryLookupMemoryCache(b *testing.B) {
+	benchmarkCountryLookup(b, &GeoIPOptions{MemoryCache: true})
+}
+
+func BenchmarkCountryLookupMMapCache(b *testing.B) {
+	if runtime.GOOS != "linux" {
+		b.Skip("memory mapped databases are only supported on Linux")
+	}
+	benchmarkCountryLookup(b, &GeoIPOptions{MMapCache: true})
+}
+
+func BenchmarkCountryLookupV6MemoryCache(b *testing.B) {
+	builder := newTestDBBuilder(CountryEditionV6, true)
+	builder.insert("2801::/16", uint(testCountryIndex(b, "UY")))
+	db := builder.openWithOptions(b, &GeoIPOptions{MemoryCache: true})
+	ip := net.ParseIP("2801::1")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.GetCountryByIP(ip); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package geoiplegacy
+
+import (
+	"net"
+	"net/netip"
+)
+
+// CityResult is the result of scanning a City Edition database for the location
+// of a network address
+type CityResult struct {
+	CountryResult
+	Region     string
+	City       string
+	PostalCode string
+	Latitude   float64
+	Longitude  float64
+	MetroCode  int // only set for US locations in Rev 1 databases
+	AreaCode   int // only set for US locations in Rev 1 databases
+}
+
+func (db *DB) isCityEdition() bool {
+	return db.Type == CityEditionRev0 ||
+		db.Type == CityEditionRev1 ||
+		db.Type == CityEditionRev0V6 ||
+		db.Type == CityEditionRev1V6
+}
+
+// extractCityRecord decodes the city record in buf
+func (db *DB) extractCityRecord(buf []byte) (*CityResult, error) {
+	if len(buf) < 1 {
+		return nil, ErrInvalidRecord
+	}
+	country, err := countryByIndex(int(buf[0]))
+	if err != nil {
+		return nil, err
+	}
+	record := &CityResult{CountryResult: *country}
+	buf = buf[1:]
+
+	var region, city, postal []byte
+	if region, buf, err = nextString(buf); err != nil {
+		return nil, err
+	}
+	if city, buf, err = nextString(buf); err != nil {
+		return nil, err
+	}
+	if postal, buf, err = nextString(buf); err != nil {
+		return nil, err
+	}
+	record.Region = string(region)
+	record.City = db.decodeString(city)
+	record.PostalCode = string(postal)
+
+	if len(buf) < 6 {
+		return nil, ErrInvalidRecord
+	}
+	record.Latitude = float64(readUint24(buf))/10000 - 180
+	record.Longitude = float64(readUint24(buf[3:]))/10000 - 180
+
+	// area code and metro code are only in post April 2002 databases and for US
+	// locations
+	if (db.Type == CityEditionRev1 || db.Type == CityEditionRev1V6) && record.Code == "US" {
+		if len(buf) < 9 {
+			return nil, ErrInvalidRecord
+		}
+		metroAreaCombo := int(readUint24(buf[6:]))
+		record.MetroCode = metroAreaCombo / 1000
+		record.AreaCode = metroAreaCombo % 1000
+	}
+	return record, nil
+}
+
+// GetCityByIP scans a City Edition database for the record of the given IP address.
+// If the address is not in the database, ErrRecordNotFound is returned
+func (db *DB) GetCityByIP(ip net.IP) (*CityResult, error) {
+	city, _, err := db.GetCityAndNetworkByIP(ip)
+	return city, err
+}
+
+// GetCityAndNetworkByIP scans a City Edition database for the record of the given
+// IP address, returning it and the network containing the address, which all
+// have the same record. If the address is not in the database, the network is
+// returned with ErrRecordNotFound
+func (db *DB) GetCityAndNetworkByIP(ip net.IP) (*CityResult, netip.Prefix, error) {
+	db.beginLookup()
+	defer db.endLookup()
+	if !db.isCityEdition() {
+		return nil, netip.Prefix{}, db.invalidTypeError(CityEditionRev1)
+	}
+	seek, network, err := db.seekIP(ip)
+	if err != nil {
+		return nil, netip.Prefix{}, err
+	}
+	buf, err := db.readRecord(seek, FullRecordLength)
+	if err != nil {
+		return nil, network, err
+	}
+	city, err := db.extractCityRecord(buf)
+	if err != nil {
+		return nil, netip.Prefix{}, err
+	}
+	return city, network, nil
+}
+
+// GetCityByAddr scans a City Edition database for the given IP address or domain.
+// If a domain is passed to it, it tries to resolve it to an IP, then looks that up.
+func (db *DB) GetCityByAddr(addr string) (*CityResult, error) {
+	ips, err := net.LookupIP(addr)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetCityByIP(ips[0])
+}
@@ -0,0 +1,99 @@
+package geoiplegacy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCityRev1ByIPv4(t *testing.T) {
+	b := newTestDBBuilder(CityEditionRev1, false)
+	mountainView := b.addRecord(testCityRecord(testCountryIndex(t, "US"),
+		"CA", "Mountain View", "94043", 37.4192, -122.0574, 807650))
+	koln := b.addRecord(testCityRecord(testCountryIndex(t, "DE"),
+		"07", "K\xf6ln", "", 50.9333, 6.95, 0))
+	b.insert("8.8.8.0/24", mountainView)
+	b.insert("81.91.0.0/16", koln)
+	db := b.open(t)
+
+	if !assert.Equal(t, CityEditionRev1, db.Type) {
+		return
+	}
+
+	city, err := db.GetCityByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", city.Code)
+	assert.Equal(t, "United States", city.NameASCII)
+	assert.Equal(t, "NA", city.Continent)
+	assert.Equal(t, "CA", city.Region)
+	assert.Equal(t, "Mountain View", city.City)
+	assert.Equal(t, "94043", city.PostalCode)
+	assert.InDelta(t, 37.4192, city.Latitude, 0.0001)
+	assert.InDelta(t, -122.0574, city.Longitude, 0.0001)
+	assert.Equal(t, 807, city.MetroCode)
+	assert.Equal(t, 650, city.AreaCode)
+
+	city, err = db.GetCityByIP(net.ParseIP("81.91.170.12"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "DE", city.Code)
+	assert.Equal(t, "07", city.Region)
+	assert.Equal(t, "K\xf6ln", city.City)
+	assert.Equal(t, "", city.PostalCode)
+	assert.Equal(t, 0, city.MetroCode)
+	assert.Equal(t, 0, city.AreaCode)
+
+	db.Charset = Charset_UTF_8
+	city, err = db.GetCityByIP(net.ParseIP("81.91.170.12"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Köln", city.City)
+
+	_, err = db.GetCityByAddr("127.0.0.1")
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestCityRev0IgnoresMetroCode(t *testing.T) {
+	b := newTestDBBuilder(CityEditionRev0, false)
+	b.insert("8.8.8.0/24", b.addRecord(testCityRecord(testCountryIndex(t, "US"),
+		"CA", "Mountain View", "94043", 37.4192, -122.0574, 0)))
+	db := b.open(t)
+
+	city, err := db.GetCityByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Mountain View", city.City)
+	assert.Equal(t, 0, city.MetroCode)
+	assert.Equal(t, 0, city.AreaCode)
+}
+
+func TestCityRev1ByIPv6(t *testing.T) {
+	b := newTestDBBuilder(CityEditionRev1V6, true)
+	b.insert("2801::/16", b.addRecord(testCityRecord(testCountryIndex(t, "UY"),
+		"10", "Montevideo", "", -34.8581, -56.1708, 0)))
+	db := b.open(t)
+
+	city, err := db.GetCityByAddr("2801::1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "UY", city.Code)
+	assert.Equal(t, "Montevideo", city.City)
+	assert.InDelta(t, -34.8581, city.Latitude, 0.0001)
+	assert.InDelta(t, -56.1708, city.Longitude, 0.0001)
+}
+
+func TestCityInvalidDBType(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	b.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	db := b.open(t)
+
+	_, err := db.GetCityByAddr("8.8.8.8")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
@@ -2,8 +2,8 @@ package geoiplegacy
 
 import (
 	"errors"
-	"fmt"
 	"net"
+	"net/netip"
 )
 
 var (
@@ -12,8 +12,9 @@ var (
 )
 
 type CombinedDB struct {
-	v4DB *DB
-	v6DB *DB
+	v4DB     *DB
+	v6DB     *DB
+	Resolver Resolver // used by GetCountryByHostContext, net.DefaultResolver if nil
 }
 
 func OpenCombinedDB(path4, path6 string) (*CombinedDB, error) {
@@ -48,23 +49,60 @@ func (db *CombinedDB) DBv6Path() (string, error) {
 	return db.v6DB.path, nil
 }
 
-func (db *CombinedDB) GetCountryByAddr(addr string) (*CountryResult, error) {
-	ips, err := net.LookupIP(addr)
-	if err != nil {
-		return nil, err
+// dbForIP returns the database for the address family of ip
+func (db *CombinedDB) dbForIP(ip net.IP) (*DB, error) {
+	if ip == nil {
+		return nil, ErrInvalidIP
 	}
-	ip := ips[0]
-	fmt.Println(ip)
 	if ip.To4() == nil {
 		if db.v6DB == nil {
 			return nil, ErrIPv6NotInitialized
 		}
-		return db.v6DB.getCountryByIP(ip)
+		return db.v6DB, nil
 	}
 	if db.v4DB == nil {
 		return nil, ErrIPv4NotInitialized
 	}
-	return db.v4DB.getCountryByIP(ip)
+	return db.v4DB, nil
+}
+
+// GetCountryByAddr scans the database for the given IP address or domain.
+// If a domain is passed to it, it tries to resolve it to an IP, then looks that up.
+func (db *CombinedDB) GetCountryByAddr(addr string) (*CountryResult, error) {
+	ips, err := net.LookupIP(addr)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetCountryByIP(ips[0])
+}
+
+// GetCountryByIP scans the database for the given IP address without resolving
+// anything
+func (db *CombinedDB) GetCountryByIP(ip net.IP) (*CountryResult, error) {
+	gi, err := db.dbForIP(ip)
+	if err != nil {
+		return nil, err
+	}
+	return gi.GetCountryByIP(ip)
+}
+
+// GetCountryByNetIP scans the database for the given address
+func (db *CombinedDB) GetCountryByNetIP(addr netip.Addr) (*CountryResult, error) {
+	if !addr.IsValid() {
+		return nil, ErrInvalidIP
+	}
+	return db.GetCountryByIP(net.IP(addr.AsSlice()))
+}
+
+// ParseAndLookup scans the database for the given IP address. Unlike
+// GetCountryByAddr, it returns an error instead of resolving addr if it isn't
+// an IP address
+func (db *CombinedDB) ParseAndLookup(addr string) (*CountryResult, error) {
+	ip, err := parseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetCountryByNetIP(ip)
 }
 
 func (db *CombinedDB) Close() error {
@@ -74,9 +112,9 @@ func (db *CombinedDB) Close() error {
 	}
 	if db.v6DB != nil {
 		if err == nil {
-			err = db.Close()
+			err = db.v6DB.Close()
 		} else {
-			db.Close()
+			db.v6DB.Close()
 		}
 	}
 	return err
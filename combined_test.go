@@ -1,6 +1,9 @@
 package geoiplegacy
 
 import (
+	"context"
+	"net"
+	"net/netip"
 	"os"
 	"testing"
 
@@ -28,13 +31,65 @@ func TestLookupDomainCountry(t *testing.T) {
 	if !assert.NotNil(t, db) {
 		return
 	}
-	country, err := db.GetCountryByAddr("google.com")
-	if !assert.NoError(t, err) {
+	db.Resolver = googleResolver
+	results, err := db.GetCountryByHostContext(context.Background(), "google.com")
+	if !assert.NoError(t, err) || !assert.NotEmpty(t, results) {
 		return
 	}
+	country := results[0].Country
 	assert.NotEqual(t, "--", country.Code)
 	assert.NotEqual(t, "--", country.Code3)
 	assert.NotEqual(t, "N/A", country.NameASCII)
 	assert.NotEqual(t, "N/A", country.NameUTF8)
 	assert.NotEqual(t, "--", country.Continent)
 }
+
+func TestCombinedLookupWithoutResolving(t *testing.T) {
+	v4 := newTestDBBuilder(CountryEdition, false)
+	v4.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	v6 := newTestDBBuilder(CountryEditionV6, true)
+	v6.insert("2801::/16", uint(testCountryIndex(t, "UY")))
+
+	db, err := OpenCombinedDB(v4.write(t), v6.write(t))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		assert.NoError(t, db.Close())
+	}()
+
+	country, err := db.GetCountryByIP(net.ParseIP("8.8.8.8"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+
+	country, err = db.GetCountryByNetIP(netip.MustParseAddr("2801::1"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "UY", country.Code)
+
+	country, err = db.ParseAndLookup("2801::1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "UY", country.Code)
+
+	_, err = db.ParseAndLookup("google.com")
+	assert.ErrorIs(t, err, ErrInvalidIP)
+}
+
+func TestCombinedMissingDB(t *testing.T) {
+	v4 := newTestDBBuilder(CountryEdition, false)
+	db, err := OpenCombinedDB(v4.write(t), "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		assert.NoError(t, db.Close())
+	}()
+
+	_, err = db.ParseAndLookup("2801::1")
+	assert.ErrorIs(t, err, ErrIPv6NotInitialized)
+}
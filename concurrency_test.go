@@ -0,0 +1,148 @@
+package geoiplegacy
+
+import (
+	"net"
+	"net/netip"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testGoroutines       = 32
+	testLookupsPerWorker = 200
+)
+
+// hammer runs lookup from many goroutines at once, reporting the first error
+func hammer(t *testing.T, lookup func() error) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan error, testGoroutines)
+	for i := 0; i < testGoroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < testLookupsPerWorker; j++ {
+				if err := lookup(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	assert.NoError(t, <-errs)
+}
+
+func TestConcurrentLookups(t *testing.T) {
+	tests := []struct {
+		name    string
+		options GeoIPOptions
+	}{
+		{"file", GeoIPOptions{}},
+		{"MemoryCache", GeoIPOptions{MemoryCache: true}},
+		{"MMapCache", GeoIPOptions{MMapCache: true}},
+		{"IndexCache", GeoIPOptions{IndexCache: true}},
+		{"CheckCache", GeoIPOptions{CheckCache: true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.options.MMapCache && runtime.GOOS != "linux" {
+				t.Skip("memory mapped databases are only supported on Linux")
+			}
+			countryDB := newTestDBBuilder(CountryEdition, false)
+			countryDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+			countryDB.insert("81.91.0.0/16", uint(testCountryIndex(t, "DE")))
+			country := countryDB.openWithOptions(t, &tc.options)
+
+			city := testCityDBBuilder(t).openWithOptions(t, &tc.options)
+
+			ispDB := newTestDBBuilder(ISPEditionV6, true)
+			ispDB.insert("2001:4860::/32", ispDB.addString("Google LLC"))
+			isp := ispDB.openWithOptions(t, &tc.options)
+
+			addrs := []netip.Addr{netip.MustParseAddr("8.8.8.8"), netip.MustParseAddr("81.91.170.12")}
+			ips := []net.IP{net.IP(addrs[0].AsSlice()), net.IP(addrs[1].AsSlice())}
+			codes := []string{"US", "DE"}
+			hammer(t, func() error {
+				for i, ip := range ips {
+					result, network, err := country.GetCountryAndNetworkByIP(ip)
+					if err != nil {
+						return err
+					}
+					if !assert.Equal(t, codes[i], result.Code) ||
+						!assert.True(t, network.Contains(addrs[i])) {
+						return nil
+					}
+				}
+				result, err := city.GetCityByIP(ips[0])
+				if err != nil {
+					return err
+				}
+				assert.Equal(t, "Mountain View", result.City)
+				org, err := isp.GetOrgByIP(net.ParseIP("2001:4860:4860::8888"))
+				if err != nil {
+					return err
+				}
+				assert.Equal(t, "Google LLC", org)
+				return nil
+			})
+		})
+	}
+}
+
+func TestConcurrentTeredoLookups(t *testing.T) {
+	b := newTestDBBuilder(CountryEditionV6, true)
+	b.insert("::808:808/128", uint(testCountryIndex(t, "US")))
+	db := b.openWithOptions(t, &GeoIPOptions{Teredo: true, MemoryCache: true})
+
+	// a Teredo address of 8.8.8.8, which is stored inverted in its last 4 bytes
+	teredo := net.ParseIP("2001:0:4136:e378:8000:63bf:f7f7:f7f7")
+	original := append(net.IP(nil), teredo...)
+	hammer(t, func() error {
+		country, err := db.GetCountryByIP(teredo)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, "US", country.Code)
+		return nil
+	})
+	assert.Equal(t, original, teredo, "the address passed to the lookup was modified")
+}
+
+func TestConcurrentReload(t *testing.T) {
+	oldDB := newTestDBBuilder(CountryEdition, false)
+	oldDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	db := oldDB.openWithOptions(t, &GeoIPOptions{CheckCache: true, MemoryCache: true})
+
+	newDB := newTestDBBuilder(CountryEdition, false)
+	newDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "DE")))
+
+	ip := net.ParseIP("8.8.8.8")
+	var replace sync.Once
+	hammer(t, func() error {
+		replace.Do(func() {
+			replaceTestDB(t, db.Path(), newDB, time.Now().Add(-2*time.Minute))
+		})
+		// force a check on every lookup, so that they race with the reload
+		db.lastModTimeCheck.Store(0)
+		country, err := db.GetCountryByIP(ip)
+		if err != nil {
+			return err
+		}
+		if country.Code != "US" && country.Code != "DE" {
+			t.Errorf("unexpected country %q", country.Code)
+		}
+		return nil
+	})
+
+	country, err := db.GetCountryByIP(ip)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "DE", country.Code)
+}
@@ -0,0 +1,65 @@
+package geoiplegacy
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+var confidenceEditions = []DBType{
+	CountryConfEdition, RegionConfEdition, CityConfEdition, PostalConfEdition,
+}
+
+// parseIntRecord parses a string record that stores a single number
+func parseIntRecord(record string) (int, error) {
+	num, err := strconv.Atoi(strings.TrimSpace(record))
+	if err != nil {
+		return 0, ErrInvalidRecord
+	}
+	return num, nil
+}
+
+// GetAccuracyRadiusByIP scans an Accuracy Radius Edition database for the radius
+// in kilometers around the location of the given IP address that it is likely to
+// be in
+func (db *DB) GetAccuracyRadiusByIP(ip net.IP) (int, error) {
+	record, err := db.getNameByIP(ip, AccuracyRadiusEdition, AccuracyRadiusEditionV6)
+	if err != nil {
+		return 0, err
+	}
+	return parseIntRecord(record)
+}
+
+// GetAccuracyRadiusByAddr scans an Accuracy Radius Edition database for the given
+// IP address or domain. If a domain is passed to it, it tries to resolve it to an
+// IP, then looks that up.
+func (db *DB) GetAccuracyRadiusByAddr(addr string) (int, error) {
+	record, err := db.getNameByAddr(addr, AccuracyRadiusEdition, AccuracyRadiusEditionV6)
+	if err != nil {
+		return 0, err
+	}
+	return parseIntRecord(record)
+}
+
+// GetConfidenceByIP scans a Country, Region, City or Postal Confidence Edition
+// database for the confidence (0-100) that the location of the given IP address
+// is correct. The field the score applies to depends on the edition, given by
+// db.Type
+func (db *DB) GetConfidenceByIP(ip net.IP) (int, error) {
+	record, err := db.getNameByIP(ip, confidenceEditions...)
+	if err != nil {
+		return 0, err
+	}
+	return parseIntRecord(record)
+}
+
+// GetConfidenceByAddr scans a Confidence Edition database for the given IP address
+// or domain. If a domain is passed to it, it tries to resolve it to an IP, then
+// looks that up.
+func (db *DB) GetConfidenceByAddr(addr string) (int, error) {
+	record, err := db.getNameByAddr(addr, confidenceEditions...)
+	if err != nil {
+		return 0, err
+	}
+	return parseIntRecord(record)
+}
@@ -0,0 +1,68 @@
+package geoiplegacy
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccuracyRadius(t *testing.T) {
+	b := newTestDBBuilder(AccuracyRadiusEdition, false)
+	b.insert("8.8.8.0/24", b.addString("1000"))
+	b.insert("81.91.0.0/16", b.addString("25"))
+	b.insert("10.0.0.0/8", b.addString("far"))
+	db := b.open(t)
+
+	radius, err := db.GetAccuracyRadiusByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1000, radius)
+
+	radius, err = db.GetAccuracyRadiusByAddr("81.91.170.12")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 25, radius)
+
+	_, err = db.GetAccuracyRadiusByAddr("10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidRecord)
+
+	_, err = db.GetConfidenceByAddr("8.8.8.8")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
+
+func TestAccuracyRadiusV6(t *testing.T) {
+	b := newTestDBBuilder(AccuracyRadiusEditionV6, true)
+	b.insert("2001:4860::/32", b.addString("50"))
+	db := b.open(t)
+
+	radius, err := db.GetAccuracyRadiusByAddr("2001:4860::1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 50, radius)
+}
+
+func TestConfidenceEditions(t *testing.T) {
+	for i, dbType := range confidenceEditions {
+		t.Run(dbType.String(), func(t *testing.T) {
+			b := newTestDBBuilder(dbType, false)
+			b.insert("8.8.8.0/24", b.addString(strconv.Itoa(90+i)))
+			db := b.open(t)
+
+			confidence, err := db.GetConfidenceByAddr("8.8.8.8")
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, 90+i, confidence)
+
+			_, err = db.GetConfidenceByAddr("10.0.0.1")
+			assert.ErrorIs(t, err, ErrRecordNotFound)
+
+			_, err = db.GetAccuracyRadiusByAddr("8.8.8.8")
+			assert.ErrorIs(t, err, ErrInvalidDBType)
+		})
+	}
+}
@@ -17,6 +17,7 @@ const (
 	StructureInfoMaxSize = 20
 	DBInfoMaxSize        = 100
 	MaxOrgRecordLength   = 300
+	FullRecordLength     = 50
 	USOffset             = 1
 	CanadaOffset         = 677
 	WorldOffset          = 1353
@@ -31,7 +32,8 @@ const (
 
 const (
 	// GeoIPProxyTypes enum
-	AnonProxy ProxyType = iota + 1
+	NoProxy ProxyType = iota
+	AnonProxy
 	HTTPXForwardedForProxy
 )
 
@@ -41,4 +43,5 @@ const (
 	DialupSpeed
 	CableDSLSpeed
 	CorporateSpeed
+	CellularSpeed // only used by Netspeed Edition Rev 1 databases
 )
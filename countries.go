@@ -1,5 +1,11 @@
 package geoiplegacy
 
+const (
+	// indexes of countries with regions in Region Edition databases
+	canadaCountryID = 38
+	usCountryID     = 225
+)
+
 var (
 	countryCodes = []string{
 		"--", "AP", "EU", "AD", "AE", "AF", "AG", "AI", "AL", "AM", "CW", "AO",
@@ -596,3 +602,20 @@ var (
 		"NA", "NA", "AF", "--",
 	}
 )
+
+// countries is built from the tables above when the package is initialised, so
+// that country lookups can return a pointer into it instead of allocating
+var countries = func() []CountryResult {
+	results := make([]CountryResult, len(countryCodes))
+	for i := range results {
+		results[i] = CountryResult{
+			ID:        i,
+			Code:      countryCodes[i],
+			Code3:     countryCode3[i],
+			NameASCII: countryNamesASCII[i],
+			NameUTF8:  countryNamesUTF8[i],
+			Continent: countryContinents[i],
+		}
+	}
+	return results
+}()
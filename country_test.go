@@ -0,0 +1,121 @@
+package geoiplegacy
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountryEditions(t *testing.T) {
+	tests := []struct {
+		dbType  DBType
+		v6      bool
+		segment uint
+		network string
+		addr    string
+		private string
+	}{
+		{CountryEdition, false, CountryBegin, "81.91.0.0/16", "81.91.170.12", "10.0.0.1"},
+		{CountryEditionV6, true, CountryBegin, "2a0b:5f80::/29", "2a0b:5f80::1", "fd00::1"},
+		{LargeCountryEdition, false, LargeCountryBegin, "81.91.0.0/16", "81.91.170.12", "10.0.0.1"},
+		{LargeCountryEditionV6, true, LargeCountryBegin, "2a0b:5f80::/29", "2a0b:5f80::1", "fd00::1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.dbType.String(), func(t *testing.T) {
+			b := newTestDBBuilder(tc.dbType, tc.v6)
+			b.insert(tc.network, uint(testCountryIndex(t, "DE")))
+			db := b.open(t)
+
+			if !assert.Equal(t, tc.dbType, db.Type) || !assert.Equal(t, []uint{tc.segment}, db.segments) {
+				return
+			}
+			assert.EqualValues(t, db.Size, db.GetIndexSize())
+
+			country, err := db.GetCountryByAddr(tc.addr)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "DE", country.Code)
+			assert.Equal(t, "DEU", country.Code3)
+			assert.Equal(t, "Germany", country.NameASCII)
+			assert.Equal(t, "EU", country.Continent)
+
+			country, err = db.GetCountryByAddr(tc.private)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "--", country.Code)
+			assert.Equal(t, "N/A", country.NameASCII)
+		})
+	}
+}
+
+func TestLargeCountryExtraIDs(t *testing.T) {
+	b := newTestDBBuilder(LargeCountryEdition, false)
+	b.insert("8.8.8.0/24", uint(testCountryIndex(t, "O1")))
+	b.insert("9.9.9.0/24", uint(len(countryCodes)))
+	b.insert("10.0.0.0/8", CountryBegin-LargeCountryBegin)
+	db := b.open(t)
+
+	country, err := db.GetCountryByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "O1", country.Code)
+	assert.Equal(t, testCountryIndex(t, "O1"), country.ID)
+
+	// IDs past the known countries are returned without codes or names
+	country, err = db.GetCountryByAddr("9.9.9.9")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, CountryResult{ID: len(countryCodes)}, *country)
+
+	country, err = db.GetCountryByAddr("10.0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, CountryResult{ID: CountryBegin - LargeCountryBegin}, *country)
+}
+
+func TestCountryByIPWithoutResolving(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	b.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	db := b.open(t)
+
+	country, err := db.GetCountryByIP(net.ParseIP("8.8.8.8"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+
+	country, err = db.GetCountryByNetIP(netip.MustParseAddr("8.8.8.8"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+
+	// IPv4-mapped IPv6 addresses are looked up as IPv4
+	country, err = db.GetCountryByNetIP(netip.MustParseAddr("::ffff:8.8.8.8"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+
+	country, err = db.ParseAndLookup("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+
+	_, err = db.ParseAndLookup("dns.google")
+	assert.ErrorIs(t, err, ErrInvalidIP)
+
+	_, err = db.GetCountryByNetIP(netip.Addr{})
+	assert.ErrorIs(t, err, ErrInvalidIP)
+
+	_, err = db.GetCountryByIP(nil)
+	assert.ErrorIs(t, err, ErrInvalidIP)
+}
@@ -1,13 +1,24 @@
 package geoiplegacy
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net"
+	"net/netip"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// CountryResult is the result of scanning the database for the location of a network address
+// CountryResult is the result of scanning the database for the location of a network address.
+// Country lookups return a pointer to a table shared by all lookups, which must not be modified
 type CountryResult struct {
+	// ID is the index of the country in the database. Large Country Editions can
+	// have IDs past the known countries, which have no codes or names
+	ID        int
 	Code      string
 	Code3     string
 	NameASCII string
@@ -18,13 +29,41 @@ type CountryResult struct {
 // GeoIPOptions are used when reading the database file
 type GeoIPOptions struct {
 	IsIPv6 bool
+	// Teredo looks up Teredo addresses (2001:0::/32) as the IPv4 address of
+	// their client. The networks returned for them are the network of that IPv4
+	// address (as ::a.b.c.d), which doesn't contain the Teredo address
 	Teredo bool
+	// MemoryCache reads the whole database into memory when it is opened, so that
+	// lookups don't need to read from the file
+	MemoryCache bool
+	// MMapCache maps the database into memory (Linux only), so that lookups don't
+	// need to read from the file and processes using the same database share its
+	// pages. It takes precedence over MemoryCache. Databases that aren't opened
+	// from a file with OpenDB or OpenReaderAt are read into memory instead
+	MMapCache bool
+	// IndexCache reads only the search tree into memory when the database is
+	// opened, while the records of City, Organization and similar editions are
+	// still read from the file. It is ignored if MMapCache or MemoryCache is set
+	IndexCache bool
+	// CheckCache checks at most once a second whether the database file has been
+	// modified, and reloads it once it has been left untouched for a minute. It
+	// only applies to databases opened with OpenDB. If the file can't be
+	// reloaded, the current database is kept, and the error is returned by
+	// LastReloadError. Since a reload replaces the Type, ModTime, Size and
+	// RecordLength fields of the DB, they must not be read while lookups may be
+	// running. Edition can be used instead of Type
+	CheckCache bool
 }
 
-// DB represents a legacy GeoIP database, usually having a .dat extension
+// DB represents a legacy GeoIP database, usually having a .dat extension. Lookups
+// keep no state in the DB, so it is safe to share between goroutines
 type DB struct {
-	file             *os.File
+	reader           io.ReaderAt // the database file, or another source the database was opened from
+	closer           io.Closer   // closed by Close, may be nil
 	path             string
+	cache            []byte // the whole database if it is cached or mapped in memory
+	mmapped          bool
+	indexCache       []byte // the search tree if only it is cached in memory
 	segments         []uint
 	Type             DBType
 	ModTime          time.Time
@@ -32,10 +71,26 @@ type DB struct {
 	Size             int64
 	RecordLength     uint8
 	Charset          Charset
-	netMask          int // netmask of last lookup, set using depth in the seek methods
-	lastModTimeCheck time.Time
+	Resolver         Resolver     // used by GetCountryByHostContext, net.DefaultResolver if nil
+	lastModTimeCheck atomic.Int64 // unix time in nanoseconds of the last CheckCache check
+	failedModTime    atomic.Int64 // unix time in nanoseconds of the modification time of a file that failed to reload
+	reloadErrMu      sync.Mutex
+	reloadErr        error
+	reloading        atomic.Bool  // set while the database is being reloaded, so that only one reload runs at a time
+	closed           atomic.Bool  // set by Close while holding mu, so that the database isn't reloaded afterwards
+	mu               sync.RWMutex // held for reading during lookups if CheckCache is set
 }
 
+// Edition returns the edition of the database. Unlike reading Type, it is safe
+// to call while the database may be reloaded because CheckCache is set
+func (db *DB) Edition() DBType {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.Type
+}
+
+// Path returns the path of the database file, or an empty string if it wasn't
+// opened with OpenDB
 func (db *DB) Path() string {
 	return db.path
 }
@@ -53,15 +108,23 @@ func (db *DB) setupSegments() error {
 	db.segments = nil
 	db.Type = InvalidVersion
 	db.RecordLength = StandardRecordLength
+	if db.Size < 3 {
+		// too short to contain the structure info delimiter
+		return nil
+	}
 
 	var err error
 	for i := 0; i < StructureInfoMaxSize; i++ {
-		if _, err = db.file.ReadAt(delim, offset); err != nil {
+		if _, err = db.readAt(delim, offset); err != nil {
 			return err
 		}
 		offset += 3
 		if delim[0] == 255 && delim[1] == 255 && delim[2] == 255 {
-			if _, err = db.file.Read(byteBuf); err != nil {
+			if _, err = db.readAt(byteBuf, offset); errors.Is(err, io.EOF) {
+				// the delimiter is at the end of the file, without a database type
+				db.segments = nil
+				return nil
+			} else if err != nil {
 				return err
 			}
 			offset++
@@ -96,6 +159,7 @@ func (db *DB) setupSegments() error {
 				db.Type == NetSpeedEditionRev1 ||
 				db.Type == NetSpeedEditionRev1V6 ||
 				db.Type == LocationAEdition ||
+				db.Type == LocationAEditionV6 ||
 				db.Type == AccuracyRadiusEdition ||
 				db.Type == AccuracyRadiusEditionV6 ||
 				db.Type == CityEditionRev0V6 ||
@@ -108,16 +172,16 @@ func (db *DB) setupSegments() error {
 				db.segments = make([]uint, 1)
 				db.segments[0] = 0
 				segmentRecordLength := SegmentRecordLength
-				n, err := db.file.Read(buf)
-				if err != nil {
-					return err
-				}
+				n, err := db.readAt(buf[:segmentRecordLength], offset)
 				if n != segmentRecordLength {
 					db.segments = nil
 					return ErrSegmentNotRead
 				}
+				if err != nil && !errors.Is(err, io.EOF) {
+					return err
+				}
 				for j := 0; j < segmentRecordLength; j++ {
-					db.segments[0] += uint(buf[j] << (j * 8))
+					db.segments[0] += uint(buf[j]) << (j * 8)
 				}
 
 				//  the record_length must be correct from here on
@@ -126,7 +190,7 @@ func (db *DB) setupSegments() error {
 					db.Type == DomainEdition ||
 					db.Type == DomainEditionV6 ||
 					db.Type == ISPEdition ||
-					db.Type == CityConfidenceDistISPOrgEdition {
+					db.Type == ISPEditionV6 {
 					db.RecordLength = OrgRecordLength
 				}
 			}
@@ -169,78 +233,363 @@ func (db *DB) hasContent() bool {
 // GetIndexSize returns the size of the database index. If it is negative,
 // something has gone wrong during a read
 func (db *DB) GetIndexSize() int32 {
-	var segment uint
-	var indexSize int32
 	if !db.hasContent() {
 		return int32(db.Size)
 	}
-	segment = db.segments[0]
-	indexSize = int32(uint8(segment) * db.RecordLength * 2)
+	indexSize := int64(db.segments[0]) * int64(db.RecordLength) * 2
 
-	// check for overflow in multiplication
-	if segment != 0 && indexSize/int32(segment) != int32(db.RecordLength*2) {
+	// check for overflow
+	if indexSize > math.MaxInt32 || indexSize > db.Size {
 		return -1
 	}
-	if indexSize > int32(db.Size) {
-		return -1
+	return int32(indexSize)
+}
+
+// readAt reads len(buf) bytes from the database at the given offset, from the
+// memory cache if it is enabled, or the file otherwise
+func (db *DB) readAt(buf []byte, offset int64) (int, error) {
+	if db.cache == nil {
+		return db.reader.ReadAt(buf, offset)
+	}
+	if offset < 0 {
+		return 0, ErrInvalidRecord
+	}
+	if offset >= int64(len(db.cache)) {
+		return 0, io.EOF
 	}
-	return indexSize
+	n := copy(buf, db.cache[offset:])
+	if n < len(buf) {
+		return n, io.EOF
+	}
+	return n, nil
 }
 
-func (db *DB) checkModTime() error {
-	buf, err := db.file.Stat()
-	if err != nil {
-		return err
+// readNode returns the left and right records of the node at the given offset in
+// the tree, reading it from the memory or index cache if possible, so that
+// cached lookups don't allocate
+func (db *DB) readNode(offset int64) (uint, uint, error) {
+	recordLength := int64(db.RecordLength)
+	end := offset + 2*recordLength
+	var buf []byte
+	if db.cache != nil {
+		if end > int64(len(db.cache)) {
+			return 0, 0, fmt.Errorf(
+				"unable to read full record (read %d, expected %d)",
+				max(int64(len(db.cache))-offset, 0), end-offset)
+		}
+		buf = db.cache[offset:end]
+	} else if end <= int64(len(db.indexCache)) {
+		buf = db.indexCache[offset:end]
+	} else {
+		buf = make([]byte, end-offset)
+		n, err := db.reader.ReadAt(buf, offset)
+		if n != len(buf) {
+			return 0, 0, fmt.Errorf(
+				"unable to read full record (read %d, expected %d)",
+				n, len(buf))
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, 0, err
+		}
+	}
+
+	if recordLength == 3 {
+		// most common case is unrolled
+		return readUint24(buf), readUint24(buf[3:]), nil
+	}
+	var left, right uint
+	for i := recordLength - 1; i >= 0; i-- {
+		left = left<<8 | uint(buf[i])
+		right = right<<8 | uint(buf[recordLength+i])
+	}
+	return left, right, nil
+}
+
+// checkModTime reloads the database if CheckCache is set and the file has been
+// modified, but not within the last minute. Otherwise, it may only be loaded
+// partly (according to original library comments). If the database can't be
+// reloaded, lookups keep using the current one, the error is kept for
+// LastReloadError, and the file isn't tried again until it is modified again
+func (db *DB) checkModTime() {
+	if !db.Options.CheckCache || db.path == "" || db.closed.Load() {
+		return
 	}
-	bufMod := buf.ModTime()
 	t := time.Now()
-	if t.Sub(db.lastModTimeCheck) <= time.Second {
+	lastCheck := db.lastModTimeCheck.Load()
+	if t.Sub(time.Unix(0, lastCheck)) <= time.Second {
 		// shouldn't be called if it's been checked a second or less ago
-		return nil
+		return
 	}
-	if t.Sub(bufMod) < time.Minute {
-		// make sure the database is at least 60 seconds untouched. Otherwise,
-		// it may only be loaded partly (according to original library comments)
-		return nil
+	if !db.lastModTimeCheck.CompareAndSwap(lastCheck, t.UnixNano()) {
+		// another lookup is already checking
+		return
+	}
+
+	// stat the path rather than the open file, since updates usually replace it
+	fi, err := os.Stat(db.path)
+	if err != nil {
+		db.setReloadError(err)
+		return
+	}
+	db.mu.RLock()
+	modTime := db.ModTime
+	db.mu.RUnlock()
+	bufMod := fi.ModTime()
+	if bufMod.Equal(modTime) || t.Sub(bufMod) < time.Minute ||
+		bufMod.UnixNano() == db.failedModTime.Load() {
+		return
+	}
+	if !db.reloading.CompareAndSwap(false, true) {
+		// a reload that started more than a second ago is still opening the file
+		return
+	}
+	defer db.reloading.Store(false)
+	if err = db.reload(); err != nil {
+		db.failedModTime.Store(bufMod.UnixNano())
+		db.setReloadError(err)
+		return
 	}
+	db.failedModTime.Store(0)
+	db.setReloadError(nil)
+}
 
-	db.lastModTimeCheck = t
+func (db *DB) setReloadError(err error) {
+	db.reloadErrMu.Lock()
+	db.reloadErr = err
+	db.reloadErrMu.Unlock()
+}
 
-	return nil
+// LastReloadError returns the error of the last failed attempt to check or reload
+// the database file if CheckCache is set, or nil if it hasn't failed since the
+// database was last reloaded
+func (db *DB) LastReloadError() error {
+	db.reloadErrMu.Lock()
+	defer db.reloadErrMu.Unlock()
+	return db.reloadErr
 }
 
-func (db *DB) getCountryByID(id int) (*CountryResult, error) {
-	countryID := id - int(db.segments[0])
-	if countryID < 0 || countryID >= len(countryCodes) {
-		return nil, ErrInvalidCountryID
+// reload reopens the database file and replaces the current one with it after
+// in-flight lookups have finished. The current database is kept if the file
+// can't be opened, and the new one is discarded if the database was closed
+// while it was being opened
+func (db *DB) reload() error {
+	newDB, err := OpenDB(db.path, db.Options)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	if db.closed.Load() {
+		db.mu.Unlock()
+		return newDB.Close()
 	}
+	old := &DB{closer: db.closer, cache: db.cache, mmapped: db.mmapped}
+	db.reader = newDB.reader
+	db.closer = newDB.closer
+	db.cache = newDB.cache
+	db.mmapped = newDB.mmapped
+	db.indexCache = newDB.indexCache
+	db.segments = newDB.segments
+	db.Type = newDB.Type
+	db.ModTime = newDB.ModTime
+	db.Size = newDB.Size
+	db.RecordLength = newDB.RecordLength
+	db.mu.Unlock()
 
-	return &CountryResult{
-		Code:      countryCodes[countryID],
-		Code3:     countryCode3[countryID],
-		NameASCII: countryNamesASCII[countryID],
-		NameUTF8:  countryNamesUTF8[countryID],
-		Continent: countryContinents[countryID],
-	}, nil
+	return old.Close()
 }
 
-func (db *DB) getCountryByIP(ip net.IP) (*CountryResult, error) {
-	var countryID int
-	var err error
-	if len(ip.To4()) == 4 {
-		if countryID, err = db.idByAddrv4(ip); err != nil {
-			return nil, err
+// beginLookup reloads the database if needed and, if CheckCache is set, locks
+// it for reading so that it isn't replaced during the lookup. endLookup must
+// be called when the lookup is finished
+func (db *DB) beginLookup() {
+	db.checkModTime()
+	if db.Options.CheckCache {
+		db.mu.RLock()
+	}
+}
+
+func (db *DB) endLookup() {
+	if db.Options.CheckCache {
+		db.mu.RUnlock()
+	}
+}
+
+// isEdition returns true if the database is one of the given editions
+func (db *DB) isEdition(editions ...DBType) bool {
+	for _, edition := range editions {
+		if db.Type == edition {
+			return true
 		}
+	}
+	return false
+}
+
+func (db *DB) isCountryEdition() bool {
+	return db.isEdition(CountryEdition, CountryEditionV6, LargeCountryEdition, LargeCountryEditionV6)
+}
+
+// getIDByIP returns the value stored directly in the tree for the given IP, if the
+// database is one of the given editions. It is used by editions that don't have
+// a record segment
+func (db *DB) getIDByIP(ip net.IP, editions ...DBType) (int, error) {
+	db.beginLookup()
+	defer db.endLookup()
+	if !db.isEdition(editions...) {
+		return 0, db.invalidTypeError(editions[0])
+	}
+	seek, _, err := db.seekIP(ip)
+	if err != nil {
+		return 0, err
+	}
+	return seek - int(db.segments[0]), nil
+}
+
+// invalidTypeError returns an error describing that the database's edition
+// can't be used for the attempted lookup
+func (db *DB) invalidTypeError(expected DBType) error {
+	return fmt.Errorf("%w %s, expected %s",
+		ErrInvalidDBType, db.Type.String(), expected.String())
+}
+
+// isV6Edition returns true if the database's tree is searched with 128-bit IPv6
+// addresses rather than 32-bit IPv4 addresses
+func (db *DB) isV6Edition() bool {
+	return db.isEdition(
+		CountryEditionV6, LargeCountryEditionV6,
+		CityEditionRev0V6, CityEditionRev1V6,
+		OrgEditionV6, ISPEditionV6, DomainEditionV6,
+		RegistrarEditionV6, UserTypeEditionV6, ASNEditionV6,
+		NetSpeedEditionRev1V6, AccuracyRadiusEditionV6, LocationAEditionV6,
+	)
+}
+
+// seekIP traverses the database tree for the given IP, returning the record it
+// points to and the network containing the IP. The width of the tree depends on
+// the edition: IPv6 editions store IPv4 networks as IPv4-compatible addresses
+// (::a.b.c.d), and IPv6 addresses can't be looked up in IPv4 editions
+func (db *DB) seekIP(ip net.IP) (int, netip.Prefix, error) {
+	if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+		return 0, netip.Prefix{}, ErrInvalidIP
+	}
+	ip4 := ip.To4()
+	if !db.isV6Edition() {
+		if ip4 == nil {
+			return 0, netip.Prefix{}, fmt.Errorf("%w in %s", ErrNotIPv4, db.Type.String())
+		}
+		seek, netMask, err := db.seekRecordv4(ipv4ToNumber(ip4))
+		if err != nil {
+			return 0, netip.Prefix{}, err
+		}
+		return seek, netip.PrefixFrom(netip.AddrFrom4([4]byte(ip4)), netMask).Masked(), nil
+	}
+
+	var ipNum uint128
+	if ip4 != nil {
+		ipNum = uint128{lo: uint64(ipv4ToNumber(ip4))}
 	} else {
-		if countryID, err = db.idByAddrv6(ip); err != nil {
-			return nil, err
+		ipNum = uint128FromIP(ip)
+		if db.Options.Teredo {
+			ipNum = ipNum.teredo()
 		}
 	}
+	seek, netMask, err := db.seekRecordv6(ipNum)
+	if err != nil {
+		return 0, netip.Prefix{}, err
+	}
+	if ip4 != nil && netMask >= 96 {
+		// return the IPv4 network, so that it contains the address looked up
+		return seek, netip.PrefixFrom(netip.AddrFrom4([4]byte(ip4)), netMask-96).Masked(), nil
+	}
+	return seek, netip.PrefixFrom(ipNum.addr(), netMask).Masked(), nil
+}
+
+// readRecord reads up to maxLen bytes of the record in the second segment that
+// the seek result points to. It may return fewer bytes if the record is at the
+// end of the file
+func (db *DB) readRecord(seek int, maxLen int) ([]byte, error) {
+	if uint(seek) == db.segments[0] {
+		return nil, ErrRecordNotFound
+	}
+	pointer := int64(seek) + int64(2*int(db.RecordLength)-1)*int64(db.segments[0])
+	if pointer >= db.Size {
+		return nil, ErrInvalidRecord
+	}
+	if db.cache != nil {
+		return db.cache[pointer:min(pointer+int64(maxLen), int64(len(db.cache)))], nil
+	}
+	buf := make([]byte, maxLen)
+	n, err := db.readAt(buf, pointer)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func (db *DB) getCountryByID(id int) (*CountryResult, error) {
+	index := id - int(db.segments[0])
+	if index >= len(countries) && db.isEdition(LargeCountryEdition, LargeCountryEditionV6) {
+		// Large Country Editions have room for more IDs than there are known
+		// countries, so only the ID is returned for them
+		return &CountryResult{ID: index}, nil
+	}
+	return countryByIndex(index)
+}
+
+// countryByIndex returns the country at the given index of the country tables.
+// The result points into a table shared by all lookups, so it must not be modified
+func countryByIndex(countryID int) (*CountryResult, error) {
+	if countryID < 0 || countryID >= len(countries) {
+		return nil, fmt.Errorf("%w %d", ErrInvalidCountryID, countryID)
+	}
+	return &countries[countryID], nil
+}
+
+// GetCountryByIP scans the database for the given IP address without resolving
+// anything
+func (db *DB) GetCountryByIP(ip net.IP) (*CountryResult, error) {
+	country, _, err := db.GetCountryAndNetworkByIP(ip)
+	return country, err
+}
+
+// GetCountryAndNetworkByIP scans the database for the given IP address, returning
+// its country and the network containing it, which all have the same country.
+// If Teredo is set, the network of a Teredo address is that of its IPv4 address
+func (db *DB) GetCountryAndNetworkByIP(ip net.IP) (*CountryResult, netip.Prefix, error) {
+	db.beginLookup()
+	defer db.endLookup()
+	return db.countryAndNetworkByIP(ip)
+}
 
-	if countryID > 0 {
-		return db.getCountryByID(countryID)
+// countryAndNetworkByIP is GetCountryAndNetworkByIP for callers that have
+// already called beginLookup
+func (db *DB) countryAndNetworkByIP(ip net.IP) (*CountryResult, netip.Prefix, error) {
+	if !db.isCountryEdition() {
+		return nil, netip.Prefix{}, db.invalidTypeError(CountryEdition)
+	}
+	countryID, network, err := db.seekIP(ip)
+	if err != nil {
+		return nil, netip.Prefix{}, err
+	}
+
+	if countryID <= 0 {
+		return nil, netip.Prefix{}, ErrInvalidCountryID
+	}
+	country, err := db.getCountryByID(countryID)
+	if err != nil {
+		return nil, netip.Prefix{}, err
 	}
-	return nil, ErrInvalidCountryID
+	return country, network, nil
+}
+
+// GetNetworkByIP returns the network in the database containing the given IP
+// address, which all have the same record. It can be used with any edition. If
+// Teredo is set, the network of a Teredo address is that of its IPv4 address
+func (db *DB) GetNetworkByIP(ip net.IP) (netip.Prefix, error) {
+	db.beginLookup()
+	defer db.endLookup()
+	_, network, err := db.seekIP(ip)
+	return network, err
 }
 
 // GetCountryByAddr scans the database for the given IP address or domain.
@@ -251,13 +600,50 @@ func (db *DB) GetCountryByAddr(addr string) (*CountryResult, error) {
 		return nil, err
 	}
 
-	return db.getCountryByIP(ips[0])
+	return db.GetCountryByIP(ips[0])
+}
+
+// GetCountryByNetIP scans the database for the given address
+func (db *DB) GetCountryByNetIP(addr netip.Addr) (*CountryResult, error) {
+	if !addr.IsValid() {
+		return nil, ErrInvalidIP
+	}
+	// As16 keeps the address on the stack, unlike AsSlice, and IPv4 addresses
+	// are still recognised as such by net.IP
+	ip := addr.As16()
+	return db.GetCountryByIP(ip[:])
+}
+
+// ParseAndLookup scans the database for the given IP address. Unlike
+// GetCountryByAddr, it returns an error instead of resolving addr if it isn't
+// an IP address
+func (db *DB) ParseAndLookup(addr string) (*CountryResult, error) {
+	ip, err := parseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetCountryByNetIP(ip)
 }
 
-// Close closes the database file if it is not nil
+// Close unmaps the database if it is memory mapped and closes the database file
+// or other source it was opened from, if it can be closed. It must not be called
+// while lookups are in progress, unless CheckCache is set, in which case it waits
+// for them to finish
 func (db *DB) Close() error {
-	if db.file == nil {
-		return nil
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.closed.Store(true)
+	var err error
+	if db.mmapped {
+		err = munmapFile(db.cache)
+		db.cache = nil
+		db.mmapped = false
+	}
+	if db.closer != nil {
+		if closeErr := db.closer.Close(); err == nil {
+			err = closeErr
+		}
+		db.closer = nil
 	}
-	return db.file.Close()
+	return err
 }
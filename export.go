@@ -0,0 +1,169 @@
+package geoiplegacy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// csvQuote returns str as a quoted CSV field
+func csvQuote(str string) string {
+	return `"` + strings.ReplaceAll(str, `"`, `""`) + `"`
+}
+
+// rangeAddr returns the address of a range boundary, unmapping IPv4 addresses
+func rangeAddr(num uint128) netip.Addr {
+	return num.addr().Unmap()
+}
+
+// rangeNum returns the decimal number of a range boundary, which is 32-bit for
+// IPv4 addresses, as in the legacy CSV files
+func rangeNum(num uint128) string {
+	if addr := rangeAddr(num); addr.Is4() {
+		return strconv.FormatUint(num.lo&0xffffffff, 10)
+	}
+	return num.String()
+}
+
+// exportRanges walks the database, merging adjacent networks whose values have
+// the same key into ranges and calling emit with each of them. Networks whose key
+// is nil are skipped
+func (db *DB) exportRanges(key func(value any) any, emit func(first, last uint128, value any) error) error {
+	var first, last uint128
+	var lastKey, lastValue any
+	var emitErr error
+	err := db.Networks(func(network netip.Prefix, value any) bool {
+		k := key(value)
+		if k == nil {
+			return true
+		}
+		start, end := prefixRange(network)
+		if lastKey != nil && k == lastKey && last.add1() == start {
+			last = end
+			return true
+		}
+		if lastKey != nil {
+			if emitErr = emit(first, last, lastValue); emitErr != nil {
+				return false
+			}
+		}
+		first, last, lastKey, lastValue = start, end, k, value
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if emitErr != nil {
+		return emitErr
+	}
+	if lastKey != nil {
+		return emit(first, last, lastValue)
+	}
+	return nil
+}
+
+// checkEdition returns an error if the database isn't one of the given editions
+func (db *DB) checkEdition(editions ...DBType) error {
+	db.beginLookup()
+	defer db.endLookup()
+	if !db.isEdition(editions...) {
+		return db.invalidTypeError(editions[0])
+	}
+	return nil
+}
+
+// ExportCountryCSV writes the contents of a Country Edition database to w in the
+// legacy GeoIP country CSV layout: start IP, end IP, start number, end number,
+// country code and country name, with every field quoted. Adjacent networks in
+// the same country are merged. Networks without a known country are left out,
+// including those with Large Country Edition IDs past the known countries.
+// The numbers of IPv6 addresses are 128-bit
+func (db *DB) ExportCountryCSV(w io.Writer) error {
+	if err := db.checkEdition(CountryEdition, CountryEditionV6,
+		LargeCountryEdition, LargeCountryEditionV6); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	err := db.exportRanges(func(value any) any {
+		country := value.(*CountryResult)
+		if country.ID == 0 || country.Code == "" {
+			// the unknown country "--", or a Large Country Edition ID past the
+			// known countries, which has no code or name
+			return nil
+		}
+		return country.ID
+	}, func(first, last uint128, value any) error {
+		country := value.(*CountryResult)
+		_, err := fmt.Fprintf(bw, "%s,%s,%s,%s,%s,%s\n",
+			csvQuote(rangeAddr(first).String()), csvQuote(rangeAddr(last).String()),
+			csvQuote(rangeNum(first)), csvQuote(rangeNum(last)),
+			csvQuote(country.Code), csvQuote(country.NameUTF8))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ExportCityCSV writes the contents of a City Edition database to blocks and
+// locations in the legacy GeoIP City CSV layout. Each distinct location is given
+// a location ID, starting at 1. blocks has the columns startIpNum, endIpNum and
+// locId, with adjacent networks in the same location merged. locations has the
+// columns locId, country, region, city, postalCode, latitude, longitude,
+// metroCode and areaCode. The numbers of IPv6 addresses are 128-bit
+func (db *DB) ExportCityCSV(blocks, locations io.Writer) error {
+	if err := db.checkEdition(CityEditionRev1, CityEditionRev0,
+		CityEditionRev1V6, CityEditionRev0V6); err != nil {
+		return err
+	}
+	locIDs := make(map[CityResult]int)
+	var cities []*CityResult
+	bw := bufio.NewWriter(blocks)
+	if _, err := bw.WriteString("startIpNum,endIpNum,locId\n"); err != nil {
+		return err
+	}
+	err := db.exportRanges(func(value any) any {
+		city := value.(*CityResult)
+		locID, ok := locIDs[*city]
+		if !ok {
+			cities = append(cities, city)
+			locID = len(cities)
+			locIDs[*city] = locID
+		}
+		return locID
+	}, func(first, last uint128, value any) error {
+		_, err := fmt.Fprintf(bw, "%s,%s,%d\n",
+			rangeNum(first), rangeNum(last), locIDs[*value.(*CityResult)])
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+
+	lw := bufio.NewWriter(locations)
+	if _, err = lw.WriteString(
+		"locId,country,region,city,postalCode,latitude,longitude,metroCode,areaCode\n"); err != nil {
+		return err
+	}
+	for i, city := range cities {
+		var metroCode, areaCode string
+		if city.MetroCode != 0 || city.AreaCode != 0 {
+			metroCode = strconv.Itoa(city.MetroCode)
+			areaCode = strconv.Itoa(city.AreaCode)
+		}
+		if _, err = fmt.Fprintf(lw, "%d,%s,%s,%s,%s,%.4f,%.4f,%s,%s\n",
+			i+1, csvQuote(city.Code), csvQuote(city.Region), csvQuote(city.City),
+			csvQuote(city.PostalCode), city.Latitude, city.Longitude,
+			metroCode, areaCode); err != nil {
+			return err
+		}
+	}
+	return lw.Flush()
+}
@@ -0,0 +1,89 @@
+package geoiplegacy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCountryCSV(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	b.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	b.insert("8.8.9.0/24", uint(testCountryIndex(t, "US")))
+	b.insert("81.91.0.0/16", uint(testCountryIndex(t, "DE")))
+	db := b.open(t)
+
+	var csv strings.Builder
+	if !assert.NoError(t, db.ExportCountryCSV(&csv)) {
+		return
+	}
+	assert.Equal(t,
+		`"8.8.8.0","8.8.9.255","134744064","134744575","US","United States"`+"\n"+
+			`"81.91.0.0","81.91.255.255","1364918272","1364983807","DE","Germany"`+"\n",
+		csv.String())
+}
+
+func TestExportLargeCountryCSV(t *testing.T) {
+	b := newTestDBBuilder(LargeCountryEdition, false)
+	b.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	b.insert("9.9.9.0/24", uint(len(countryCodes)))
+	db := b.open(t)
+
+	var csv strings.Builder
+	if !assert.NoError(t, db.ExportCountryCSV(&csv)) {
+		return
+	}
+	assert.Equal(t,
+		`"8.8.8.0","8.8.8.255","134744064","134744319","US","United States"`+"\n",
+		csv.String())
+}
+
+func TestExportCountryCSVV6(t *testing.T) {
+	b := newTestDBBuilder(CountryEditionV6, true)
+	b.insert("2801::/16", uint(testCountryIndex(t, "UY")))
+	db := b.open(t)
+
+	var csv strings.Builder
+	if !assert.NoError(t, db.ExportCountryCSV(&csv)) {
+		return
+	}
+	assert.Equal(t,
+		`"2801::","2801:ffff:ffff:ffff:ffff:ffff:ffff:ffff",`+
+			`"53174312128255169743780812907543003136","53179504425113704571409343403872223231",`+
+			`"UY","Uruguay"`+"\n",
+		csv.String())
+}
+
+func TestExportCityCSV(t *testing.T) {
+	b := testCityDBBuilder(t)
+	b.insert("8.8.4.0/24", b.addRecord(testCityRecord(testCountryIndex(t, "US"),
+		"CA", "Mountain View", "94043", 37.4192, -122.0574, 807650)))
+	b.insert("81.91.0.0/16", b.addRecord(testCityRecord(testCountryIndex(t, "DE"),
+		"07", "Koln", "", 50.9333, 6.95, 0)))
+	db := b.open(t)
+
+	var blocks, locations strings.Builder
+	if !assert.NoError(t, db.ExportCityCSV(&blocks, &locations)) {
+		return
+	}
+	assert.Equal(t, "startIpNum,endIpNum,locId\n"+
+		"134743040,134743295,1\n"+
+		"134744064,134744319,1\n"+
+		"1364918272,1364983807,2\n",
+		blocks.String())
+	assert.Equal(t,
+		"locId,country,region,city,postalCode,latitude,longitude,metroCode,areaCode\n"+
+			`1,"US","CA","Mountain View","94043",37.4192,-122.0574,807,650`+"\n"+
+			`2,"DE","07","Koln","",50.9333,6.9500,,`+"\n",
+		locations.String())
+}
+
+func TestExportInvalidDBType(t *testing.T) {
+	db := testCityDBBuilder(t).open(t)
+	assert.ErrorIs(t, db.ExportCountryCSV(&strings.Builder{}), ErrInvalidDBType)
+
+	b := newTestDBBuilder(CountryEdition, false)
+	db = b.open(t)
+	assert.ErrorIs(t, db.ExportCityCSV(&strings.Builder{}, &strings.Builder{}), ErrInvalidDBType)
+}
@@ -0,0 +1,30 @@
+package geoiplegacy
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// isGzip returns true if r starts with the gzip magic number
+func isGzip(r io.ReaderAt) bool {
+	magic := make([]byte, 2)
+	n, _ := r.ReadAt(magic, 0)
+	return n == 2 && magic[0] == 0x1f && magic[1] == 0x8b
+}
+
+// openGzip decompresses the gzip-compressed database read from r into memory
+// and opens it
+func openGzip(r io.Reader, options *GeoIPOptions) (*DB, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	if err = zr.Close(); err != nil {
+		return nil, err
+	}
+	return OpenBytes(data, options)
+}
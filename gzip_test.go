@@ -0,0 +1,75 @@
+package geoiplegacy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipTestDB(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOpenGzipDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLiteCity.dat.gz")
+	if err := os.WriteFile(path, gzipTestDB(t, testCityDBBuilder(t).bytes()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := OpenDB(path, &GeoIPOptions{IndexCache: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, path, db.Path())
+	assert.NotNil(t, db.cache)
+	assert.Nil(t, db.indexCache)
+	assertTestCity(t, db)
+	assert.NoError(t, db.Close())
+}
+
+func TestOpenGzipFS(t *testing.T) {
+	data := gzipTestDB(t, testCityDBBuilder(t).bytes())
+	fsys := fstest.MapFS{
+		"GeoLiteCity.dat.gz": &fstest.MapFile{Data: data},
+	}
+
+	db, err := OpenFS(fsys, "GeoLiteCity.dat.gz", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assertTestCity(t, db)
+	assert.NoError(t, db.Close())
+
+	db, err = OpenFS(fileOnlyFS{fsys}, "GeoLiteCity.dat.gz", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assertTestCity(t, db)
+	assert.NoError(t, db.Close())
+}
+
+func TestOpenTruncatedGzipDB(t *testing.T) {
+	data := gzipTestDB(t, testCityDBBuilder(t).bytes())
+	path := filepath.Join(t.TempDir(), "GeoLiteCity.dat.gz")
+	if err := os.WriteFile(path, data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := OpenDB(path, nil)
+	assert.Nil(t, db)
+	assert.Error(t, err)
+}
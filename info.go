@@ -0,0 +1,88 @@
+package geoiplegacy
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoDBInfo = errors.New("database has no info string")
+)
+
+// DBInfo is the information string stored near the end of the database, usually
+// containing the edition, build date and copyright
+type DBInfo struct {
+	Raw       string
+	BuildDate time.Time // zero if the info string has no date in YYYYMMDD form
+	Copyright string    // empty if the info string has no copyright notice
+}
+
+// structureInfoOffset returns the offset of the delimiter preceding the database
+// structure info, or -1 if there isn't one (pre September 2002 databases)
+func (db *DB) structureInfoOffset() (int64, error) {
+	delim := make([]byte, 3)
+	offset := db.Size - 3
+	for i := 0; i < StructureInfoMaxSize && offset >= 0; i++ {
+		if _, err := db.readAt(delim, offset); err != nil {
+			return 0, err
+		}
+		if delim[0] == 255 && delim[1] == 255 && delim[2] == 255 {
+			return offset, nil
+		}
+		offset--
+	}
+	return -1, nil
+}
+
+// parseDBInfo gets the build date and copyright from the info string
+func parseDBInfo(raw string) *DBInfo {
+	info := &DBInfo{Raw: raw}
+	for _, field := range strings.Fields(raw) {
+		if len(field) != 8 {
+			continue
+		}
+		if date, err := time.Parse("20060102", field); err == nil {
+			info.BuildDate = date
+			break
+		}
+	}
+	if i := strings.Index(raw, "Copyright"); i >= 0 {
+		info.Copyright = strings.TrimSpace(raw[i:])
+	}
+	return info
+}
+
+// Info reads the database info string, which is stored before the database
+// structure info and preceded by three null bytes
+func (db *DB) Info() (*DBInfo, error) {
+	db.beginLookup()
+	defer db.endLookup()
+	offset, err := db.structureInfoOffset()
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 {
+		// no structure info, the info string is at the end of the file
+		offset = db.Size
+	}
+
+	buf := make([]byte, 3)
+	for i := 0; i < DBInfoMaxSize; i++ {
+		start := offset - 3 - int64(i)
+		if start < 0 {
+			break
+		}
+		if _, err = db.readAt(buf, start); err != nil {
+			return nil, err
+		}
+		if buf[0] == 0 && buf[1] == 0 && buf[2] == 0 {
+			raw := make([]byte, i)
+			if _, err = db.readAt(raw, start+3); err != nil {
+				return nil, err
+			}
+			return parseDBInfo(string(raw)), nil
+		}
+	}
+	return nil, ErrNoDBInfo
+}
@@ -0,0 +1,45 @@
+package geoiplegacy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBInfo(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	b.info = "GEO-106FREE 20180327 Build 1 Copyright (c) 2018 MaxMind Inc All Rights Reserved"
+	db := b.open(t)
+
+	info, err := db.Info()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, b.info, info.Raw)
+	assert.Equal(t, time.Date(2018, time.March, 27, 0, 0, 0, 0, time.UTC), info.BuildDate)
+	assert.Equal(t, "Copyright (c) 2018 MaxMind Inc All Rights Reserved", info.Copyright)
+}
+
+func TestDBInfoTwoSegments(t *testing.T) {
+	b := newTestDBBuilder(CityEditionRev1, false)
+	b.insert("8.8.8.0/24", b.addRecord(testCityRecord(testCountryIndex(t, "US"),
+		"CA", "Mountain View", "94043", 37.4192, -122.0574, 807650)))
+	b.info = "GEO-133 20240102 Build 1"
+	db := b.open(t)
+
+	info, err := db.Info()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "GEO-133 20240102 Build 1", info.Raw)
+	assert.Equal(t, time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC), info.BuildDate)
+	assert.Equal(t, "", info.Copyright)
+}
+
+func TestDBInfoMissing(t *testing.T) {
+	db := newTestDBBuilder(CountryEdition, false).open(t)
+
+	_, err := db.Info()
+	assert.ErrorIs(t, err, ErrNoDBInfo)
+}
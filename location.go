@@ -0,0 +1,20 @@
+package geoiplegacy
+
+import (
+	"net"
+)
+
+// GetLocationIDByIP scans a LocationID ASCII Edition database for the location
+// identifier of the given IP address, which can be joined with the location
+// tables distributed alongside the database.
+// If the address is not in the database, ErrRecordNotFound is returned
+func (db *DB) GetLocationIDByIP(ip net.IP) (string, error) {
+	return db.getNameByIP(ip, LocationAEdition, LocationAEditionV6)
+}
+
+// GetLocationIDByAddr scans a LocationID ASCII Edition database for the given IP
+// address or domain. If a domain is passed to it, it tries to resolve it to an IP,
+// then looks that up.
+func (db *DB) GetLocationIDByAddr(addr string) (string, error) {
+	return db.getNameByAddr(addr, LocationAEdition, LocationAEditionV6)
+}
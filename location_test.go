@@ -0,0 +1,57 @@
+package geoiplegacy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocationIDByIPv4(t *testing.T) {
+	b := newTestDBBuilder(LocationAEdition, false)
+	b.insert("8.8.8.0/24", b.addString("2703"))
+	b.insert("81.91.0.0/16", b.addString("48091"))
+	db := b.open(t)
+
+	if !assert.Equal(t, LocationAEdition, db.Type) {
+		return
+	}
+
+	locationID, err := db.GetLocationIDByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "2703", locationID)
+
+	locationID, err = db.GetLocationIDByAddr("81.91.170.12")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "48091", locationID)
+
+	_, err = db.GetLocationIDByAddr("10.0.0.1")
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestLocationIDByIPv6(t *testing.T) {
+	b := newTestDBBuilder(LocationAEditionV6, true)
+	b.insert("2801::/16", b.addString("13106"))
+	db := b.open(t)
+
+	if !assert.Equal(t, LocationAEditionV6, db.Type) {
+		return
+	}
+
+	locationID, err := db.GetLocationIDByAddr("2801::1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "13106", locationID)
+}
+
+func TestLocationIDInvalidDBType(t *testing.T) {
+	b := newTestDBBuilder(CityEditionRev1, false)
+	db := b.open(t)
+
+	_, err := db.GetLocationIDByAddr("8.8.8.8")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
@@ -0,0 +1,18 @@
+//go:build linux
+
+package geoiplegacy
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the file into memory as read-only and shared, so that processes
+// using the same database share the page cache
+func mmapFile(file *os.File, size int64) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
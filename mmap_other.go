@@ -0,0 +1,15 @@
+//go:build !linux
+
+package geoiplegacy
+
+import (
+	"os"
+)
+
+func mmapFile(_ *os.File, _ int64) ([]byte, error) {
+	return nil, ErrMMapUnsupported
+}
+
+func munmapFile(_ []byte) error {
+	return ErrMMapUnsupported
+}
@@ -0,0 +1,75 @@
+package geoiplegacy
+
+import (
+	"net"
+)
+
+func (ns NetSpeedValue) String() string {
+	switch ns {
+	case UnknownSpeed:
+		return "Unknown"
+	case DialupSpeed:
+		return "Dialup"
+	case CableDSLSpeed:
+		return "Cable/DSL"
+	case CorporateSpeed:
+		return "Corporate"
+	case CellularSpeed:
+		return "Cellular"
+	}
+	return "Unknown"
+}
+
+// parseNetSpeed returns the connection speed matching the Netspeed Edition Rev 1
+// string, or UnknownSpeed if it isn't recognised
+func parseNetSpeed(str string) NetSpeedValue {
+	for _, ns := range []NetSpeedValue{DialupSpeed, CableDSLSpeed, CorporateSpeed, CellularSpeed} {
+		if ns.String() == str {
+			return ns
+		}
+	}
+	return UnknownSpeed
+}
+
+// GetNetSpeedByIP scans a Netspeed Edition database for the connection speed of
+// the given IP address
+func (db *DB) GetNetSpeedByIP(ip net.IP) (NetSpeedValue, error) {
+	id, err := db.getIDByIP(ip, NetSpeedEdition)
+	if err != nil {
+		return UnknownSpeed, err
+	}
+	return NetSpeedValue(id), nil
+}
+
+// GetNetSpeedByAddr scans a Netspeed Edition database for the given IP address or domain.
+// If a domain is passed to it, it tries to resolve it to an IP, then looks that up.
+func (db *DB) GetNetSpeedByAddr(addr string) (NetSpeedValue, error) {
+	ips, err := net.LookupIP(addr)
+	if err != nil {
+		return UnknownSpeed, err
+	}
+	return db.GetNetSpeedByIP(ips[0])
+}
+
+// GetNetSpeedRev1ByIP scans a Netspeed Edition Rev 1 database for the connection
+// type of the given IP address, returning the string stored in the database (for
+// example "Cable/DSL" or "Cellular") and the matching NetSpeedValue. If the string
+// isn't recognised, UnknownSpeed is returned with it
+func (db *DB) GetNetSpeedRev1ByIP(ip net.IP) (string, NetSpeedValue, error) {
+	str, err := db.getNameByIP(ip, NetSpeedEditionRev1, NetSpeedEditionRev1V6)
+	if err != nil {
+		return "", UnknownSpeed, err
+	}
+	return str, parseNetSpeed(str), nil
+}
+
+// GetNetSpeedRev1ByAddr scans a Netspeed Edition Rev 1 database for the given IP
+// address or domain. If a domain is passed to it, it tries to resolve it to an IP,
+// then looks that up.
+func (db *DB) GetNetSpeedRev1ByAddr(addr string) (string, NetSpeedValue, error) {
+	str, err := db.getNameByAddr(addr, NetSpeedEditionRev1, NetSpeedEditionRev1V6)
+	if err != nil {
+		return "", UnknownSpeed, err
+	}
+	return str, parseNetSpeed(str), nil
+}
@@ -0,0 +1,87 @@
+package geoiplegacy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetSpeed(t *testing.T) {
+	b := newTestDBBuilder(NetSpeedEdition, false)
+	b.insert("1.2.3.0/24", uint(DialupSpeed))
+	b.insert("8.8.8.0/24", uint(CorporateSpeed))
+	db := b.open(t)
+
+	speed, err := db.GetNetSpeedByAddr("1.2.3.4")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, DialupSpeed, speed)
+	assert.Equal(t, "Dialup", speed.String())
+
+	speed, err = db.GetNetSpeedByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, CorporateSpeed, speed)
+
+	speed, err = db.GetNetSpeedByAddr("10.0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, UnknownSpeed, speed)
+
+	_, err = db.GetCountryByAddr("8.8.8.8")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+
+	_, err = db.GetProxyTypeByAddr("8.8.8.8")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
+
+func TestNetSpeedRev1ByIPv4(t *testing.T) {
+	b := newTestDBBuilder(NetSpeedEditionRev1, false)
+	b.insert("1.2.3.0/24", b.addString("Cable/DSL"))
+	b.insert("5.6.0.0/16", b.addString("Cellular"))
+	b.insert("8.8.8.0/24", b.addString("Satellite"))
+	db := b.open(t)
+
+	str, speed, err := db.GetNetSpeedRev1ByAddr("1.2.3.4")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Cable/DSL", str)
+	assert.Equal(t, CableDSLSpeed, speed)
+
+	str, speed, err = db.GetNetSpeedRev1ByAddr("5.6.7.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Cellular", str)
+	assert.Equal(t, CellularSpeed, speed)
+
+	str, speed, err = db.GetNetSpeedRev1ByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Satellite", str)
+	assert.Equal(t, UnknownSpeed, speed)
+
+	_, _, err = db.GetNetSpeedRev1ByAddr("10.0.0.1")
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = db.GetNetSpeedByAddr("1.2.3.4")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
+
+func TestNetSpeedRev1ByIPv6(t *testing.T) {
+	b := newTestDBBuilder(NetSpeedEditionRev1V6, true)
+	b.insert("2600:380::/27", b.addString("Cellular"))
+	db := b.open(t)
+
+	str, speed, err := db.GetNetSpeedRev1ByAddr("2600:380::1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Cellular", str)
+	assert.Equal(t, CellularSpeed, speed)
+}
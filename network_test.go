@@ -0,0 +1,181 @@
+package geoiplegacy
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountryAndNetwork(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	b.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	db := b.open(t)
+
+	country, network, err := db.GetCountryAndNetworkByIP(net.ParseIP("8.8.8.8"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+	assert.Equal(t, netip.MustParsePrefix("8.8.8.0/24"), network)
+
+	// the tree only splits as far as needed to separate 10.0.0.1 from 8.8.8.0/24
+	country, network, err = db.GetCountryAndNetworkByIP(net.ParseIP("10.0.0.1"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "--", country.Code)
+	assert.Equal(t, netip.MustParsePrefix("10.0.0.0/7"), network)
+
+	network, err = db.GetNetworkByIP(net.ParseIP("8.8.8.200"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, netip.MustParsePrefix("8.8.8.0/24"), network)
+}
+
+func TestCountryAndNetworkV6(t *testing.T) {
+	b := newTestDBBuilder(CountryEditionV6, true)
+	b.insert("2801::/16", uint(testCountryIndex(t, "UY")))
+	db := b.open(t)
+
+	country, network, err := db.GetCountryAndNetworkByIP(net.ParseIP("2801::1"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "UY", country.Code)
+	assert.Equal(t, netip.MustParsePrefix("2801::/16"), network)
+}
+
+func TestCityAndNetwork(t *testing.T) {
+	b := testCityDBBuilder(t)
+	db := b.open(t)
+
+	city, network, err := db.GetCityAndNetworkByIP(net.ParseIP("8.8.8.8"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Mountain View", city.City)
+	assert.Equal(t, netip.MustParsePrefix("8.8.8.0/24"), network)
+
+	_, network, err = db.GetCityAndNetworkByIP(net.ParseIP("8.8.9.1"))
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+	assert.Equal(t, netip.MustParsePrefix("8.8.9.0/24"), network)
+}
+
+func TestOrgAndNetwork(t *testing.T) {
+	b := newTestDBBuilder(ISPEditionV6, true)
+	b.insert("2001:4860::/32", b.addString("Google LLC"))
+	db := b.open(t)
+
+	isp, network, err := db.GetOrgAndNetworkByIP(net.ParseIP("2001:4860:4860::8888"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Google LLC", isp)
+	assert.Equal(t, netip.MustParsePrefix("2001:4860::/32"), network)
+}
+
+func TestIPv4InV6Editions(t *testing.T) {
+	city := newTestDBBuilder(CityEditionRev1V6, true)
+	city.insert("::1.2.3.0/120", city.addRecord(testCityRecord(testCountryIndex(t, "AU"),
+		"02", "Sydney", "2000", -33.8591, 151.2002, 0)))
+	cityDB := city.open(t)
+
+	result, network, err := cityDB.GetCityAndNetworkByIP(net.ParseIP("1.2.3.4"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Sydney", result.City)
+	assert.Equal(t, netip.MustParsePrefix("1.2.3.0/24"), network)
+
+	result, network, err = cityDB.GetCityAndNetworkByIP(net.ParseIP("::1.2.3.4"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Sydney", result.City)
+	assert.Equal(t, netip.MustParsePrefix("::1.2.3.0/120"), network)
+
+	country := newTestDBBuilder(CountryEditionV6, true)
+	country.insert("::1.2.3.0/120", uint(testCountryIndex(t, "AU")))
+	countryDB := country.open(t)
+
+	c, err := countryDB.GetCountryByIP(net.ParseIP("1.2.3.4"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "AU", c.Code)
+
+	out := make([]CountryResult, 2)
+	err = countryDB.LookupCountries([]netip.Addr{
+		netip.MustParseAddr("1.2.3.4"), netip.MustParseAddr("1.2.3.5"),
+	}, out)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "AU", out[0].Code)
+	assert.Equal(t, "AU", out[1].Code)
+
+	asn := newTestDBBuilder(ASNEditionV6, true)
+	asn.insert("::1.2.3.0/120", asn.addString("AS13335 Cloudflare, Inc."))
+	asnDB := asn.open(t)
+
+	a, err := asnDB.GetASNByIP(net.ParseIP("1.2.3.4"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualValues(t, 13335, a.Number)
+
+	network, err = countryDB.GetNetworkByIP(net.ParseIP("8.8.8.8"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, netip.MustParsePrefix("8.0.0.0/5"), network)
+
+	// networks broader than the IPv4 space are returned as IPv6 networks
+	network, err = newTestDBBuilder(CountryEditionV6, true).open(t).GetNetworkByIP(net.ParseIP("8.8.8.8"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, netip.MustParsePrefix("::/1"), network)
+}
+
+func TestIPv6InIPv4Edition(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	b.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	db := b.open(t)
+
+	_, err := db.GetCountryByIP(net.ParseIP("2801::1"))
+	assert.ErrorIs(t, err, ErrNotIPv4)
+
+	_, err = db.GetNetworkByIP(net.ParseIP("2801::1"))
+	assert.ErrorIs(t, err, ErrNotIPv4)
+}
+
+func TestRegionAndNetwork(t *testing.T) {
+	b := newTestDBBuilder(RegionEditionRev1, false)
+	b.insert("24.48.0.0/16", CanadaOffset+testRegionOffset("QC"))
+	db := b.open(t)
+
+	region, network, err := db.GetRegionAndNetworkByIP(net.ParseIP("24.48.1.1"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "QC", region.Region)
+	assert.Equal(t, netip.MustParsePrefix("24.48.0.0/16"), network)
+}
+
+func TestTeredoNetwork(t *testing.T) {
+	b := newTestDBBuilder(CountryEditionV6, true)
+	b.insert("::808:800/120", uint(testCountryIndex(t, "US")))
+	db := b.openWithOptions(t, &GeoIPOptions{Teredo: true})
+
+	// the network is that of the IPv4 address the Teredo address maps to
+	country, network, err := db.GetCountryAndNetworkByIP(net.ParseIP("2001:0:4136:e378:8000:63bf:f7f7:f7f7"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+	assert.Equal(t, netip.MustParsePrefix("::8.8.8.0/120"), network)
+}
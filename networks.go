@@ -0,0 +1,111 @@
+package geoiplegacy
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// recordValue decodes the value that a seek result points to, according to the
+// database's edition
+func (db *DB) recordValue(seek int) (any, error) {
+	switch {
+	case db.isCountryEdition():
+		return db.getCountryByID(seek)
+	case db.isRegionEdition():
+		return db.getRegionBySeek(uint(seek))
+	case db.isCityEdition():
+		buf, err := db.readRecord(seek, FullRecordLength)
+		if err != nil {
+			return nil, err
+		}
+		return db.extractCityRecord(buf)
+	case db.Type == ProxyEdition:
+		return ProxyType(seek - int(db.segments[0])), nil
+	case db.Type == NetSpeedEdition:
+		return NetSpeedValue(seek - int(db.segments[0])), nil
+	case db.isEdition(nameEditions...):
+		return db.readName(seek)
+	}
+	return nil, db.invalidTypeError(CountryEdition)
+}
+
+// Networks walks the database tree depth-first, calling fn with every network in
+// the database, in ascending order, and its value. The walk stops when fn returns
+// false. The type of the value depends on the edition:
+//   - *CountryResult for Country editions
+//   - *RegionResult for Region editions
+//   - *CityResult for City editions
+//   - ProxyType for Proxy editions
+//   - NetSpeedValue for Netspeed editions
+//   - string for editions supported by GetOrgByIP
+//
+// Only City editions and editions supported by GetOrgByIP skip networks without a
+// record. The other editions store a value for every address, so their networks
+// cover the whole address space, including networks without known data: the
+// unknown country "--" in Country and Region editions, NoProxy in Proxy editions
+// and UnknownSpeed in Netspeed editions.
+//
+// Networks in IPv6 editions are IPv6 prefixes, including IPv4 networks stored in
+// them. The values must not be modified. fn must not call other methods of db,
+// since the database can't be reloaded during the walk
+func (db *DB) Networks(fn func(network netip.Prefix, value any) bool) error {
+	db.beginLookup()
+	defer db.endLookup()
+	if !db.canDecodeRecords() {
+		return db.invalidTypeError(CountryEdition)
+	}
+	bitLen := 32
+	if db.isV6Edition() {
+		bitLen = 128
+	}
+	_, err := db.walkNode(0, 0, bitLen, uint128{}, fn)
+	return err
+}
+
+// canDecodeRecords returns true if recordValue can decode the records of the
+// database's edition
+func (db *DB) canDecodeRecords() bool {
+	return db.isCountryEdition() || db.isRegionEdition() || db.isCityEdition() ||
+		db.isEdition(ProxyEdition, NetSpeedEdition) || db.isEdition(nameEditions...)
+}
+
+// walkNode walks the node at the given offset, which is reached by the first
+// depth bits of ipNum, returning false if fn stopped the walk
+func (db *DB) walkNode(offset uint, depth int, bitLen int, ipNum uint128, fn func(netip.Prefix, any) bool) (bool, error) {
+	recordPairLength := uint(db.RecordLength) * 2
+	byteOffset := recordPairLength * offset
+	if depth >= bitLen || byteOffset > uint(db.Size)-recordPairLength {
+		return false, fmt.Errorf(
+			"error walking db at depth %d for ipNum = %s, db possibly corrupt",
+			depth, ipNum.String())
+	}
+	left, right, err := db.readNode(int64(byteOffset))
+	if err != nil {
+		return false, err
+	}
+
+	for bit, x := range [2]uint{left, right} {
+		childNum := ipNum
+		if bit == 1 {
+			childNum = ipNum.setBit(bitLen - depth - 1)
+		}
+		if x < db.segments[0] {
+			if cont, err := db.walkNode(x, depth+1, bitLen, childNum, fn); !cont || err != nil {
+				return false, err
+			}
+			continue
+		}
+		if db.hasContent() && x == db.segments[0] {
+			// no record for the network
+			continue
+		}
+		value, err := db.recordValue(int(x))
+		if err != nil {
+			return false, err
+		}
+		if !fn(childNum.prefix(depth+1, bitLen), value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,97 @@
+package geoiplegacy
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// collectNetworks returns every network in the database and its value
+func collectNetworks(t *testing.T, db *DB) map[netip.Prefix]any {
+	t.Helper()
+	networks := make(map[netip.Prefix]any)
+	var last netip.Prefix
+	err := db.Networks(func(network netip.Prefix, value any) bool {
+		if last.IsValid() {
+			assert.True(t, last.Addr().Less(network.Addr()), "%s walked after %s", network, last)
+		}
+		last = network
+		networks[network] = value
+		return true
+	})
+	assert.NoError(t, err)
+	return networks
+}
+
+func TestCountryNetworks(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	b.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	b.insert("81.91.0.0/16", uint(testCountryIndex(t, "DE")))
+	db := b.open(t)
+
+	networks := collectNetworks(t, db)
+	assert.Equal(t, "US", networks[netip.MustParsePrefix("8.8.8.0/24")].(*CountryResult).Code)
+	assert.Equal(t, "DE", networks[netip.MustParsePrefix("81.91.0.0/16")].(*CountryResult).Code)
+	assert.Equal(t, "--", networks[netip.MustParsePrefix("128.0.0.0/1")].(*CountryResult).Code)
+
+	// every address is in exactly one network of a country database
+	var total uint64
+	for network := range networks {
+		total += 1 << (32 - network.Bits())
+	}
+	assert.Equal(t, uint64(1)<<32, total)
+
+	var walked int
+	err := db.Networks(func(netip.Prefix, any) bool {
+		walked++
+		return walked < 3
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, walked)
+}
+
+func TestCityNetworks(t *testing.T) {
+	db := testCityDBBuilder(t).open(t)
+
+	networks := collectNetworks(t, db)
+	if !assert.Len(t, networks, 1) {
+		return
+	}
+	city := networks[netip.MustParsePrefix("8.8.8.0/24")].(*CityResult)
+	assert.Equal(t, "Mountain View", city.City)
+}
+
+func TestOrgNetworksV6(t *testing.T) {
+	b := newTestDBBuilder(ISPEditionV6, true)
+	b.insert("2001:4860::/32", b.addString("Google LLC"))
+	b.insert("2801::/16", b.addString("LACNIC"))
+	db := b.open(t)
+
+	assert.Equal(t, map[netip.Prefix]any{
+		netip.MustParsePrefix("2001:4860::/32"): "Google LLC",
+		netip.MustParsePrefix("2801::/16"):      "LACNIC",
+	}, collectNetworks(t, db))
+}
+
+func TestProxyNetworks(t *testing.T) {
+	b := newTestDBBuilder(ProxyEdition, false)
+	b.insert("0.0.0.0/1", uint(AnonProxy))
+	db := b.open(t)
+
+	assert.Equal(t, map[netip.Prefix]any{
+		netip.MustParsePrefix("0.0.0.0/1"):   AnonProxy,
+		netip.MustParsePrefix("128.0.0.0/1"): NoProxy,
+	}, collectNetworks(t, db))
+}
+
+func TestCorruptTreeNetworks(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	b.nodes[0] = [2]testNodeChild{{isNode: true}, {isNode: true}}
+	db := b.open(t)
+
+	err := db.Networks(func(netip.Prefix, any) bool {
+		return true
+	})
+	assert.ErrorContains(t, err, "db possibly corrupt")
+}
@@ -1,11 +1,17 @@
 package geoiplegacy
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
 	"os"
+	"time"
 )
 
 // OpenDB opens and returns the MaxMind GeoIP v1 database, returning the database
-// and any errors
+// and any errors. If the file is gzip-compressed, it is decompressed into memory
+// and the caching options are ignored
 func OpenDB(dbPath string, options *GeoIPOptions) (*DB, error) {
 	dbFile, err := os.Open(dbPath)
 	if err != nil {
@@ -13,52 +19,161 @@ func OpenDB(dbPath string, options *GeoIPOptions) (*DB, error) {
 	}
 	fi, err := dbFile.Stat()
 	if err != nil {
+		dbFile.Close()
 		return nil, err
 	}
 
-	if options == nil {
-		options = &GeoIPOptions{}
+	if isGzip(dbFile) {
+		gi, err := openGzip(dbFile, options)
+		dbFile.Close()
+		if err != nil {
+			return nil, err
+		}
+		gi.path = dbPath
+		gi.ModTime = fi.ModTime()
+		return gi, nil
 	}
 
-	gi := &DB{
-		file:    dbFile,
-		path:    dbPath,
-		Size:    fi.Size(),
-		Options: options,
-		Charset: Charset_ISO_8859_1,
+	gi, err := openDB(dbFile, dbFile, fi.Size(), fi.ModTime(), options)
+	if err != nil {
+		return nil, err
 	}
+	gi.path = dbPath
+	return gi, nil
+}
 
-	if err = gi.setupSegments(); err != nil {
+// OpenReaderAt returns the database read from r, which must contain size bytes.
+// If r implements io.Closer, it is closed when the database is closed, but it is
+// left open if the database can't be opened. CheckCache is ignored, since the
+// database has no path to check
+func OpenReaderAt(r io.ReaderAt, size int64, options *GeoIPOptions) (*DB, error) {
+	// r is only closed by the database once it has been opened successfully
+	gi, err := openDB(r, nil, size, time.Time{}, options)
+	if err != nil {
 		return nil, err
 	}
-	if gi.segments == nil {
-		return nil, ErrNoSegments
-	}
+	gi.closer, _ = r.(io.Closer)
+	return gi, nil
+}
 
-	idxSize := gi.GetIndexSize()
-	if idxSize < 0 {
-		return nil, ErrNegativeIndex
+// OpenBytes returns the database stored in data, which is used as the memory
+// cache without being copied, so it must not be modified while the database is
+// in use. The caching options and CheckCache are ignored
+func OpenBytes(data []byte, options *GeoIPOptions) (*DB, error) {
+	gi := newDB(bytes.NewReader(data), nil, int64(len(data)), time.Time{}, options)
+	gi.cache = data
+	if err := gi.setup(); err != nil {
+		return nil, err
 	}
+	return gi, nil
+}
 
-	// if options.IndexCache {
-	// 	gi.indexCache = make([]byte, idxSize)
-	// 	var n int
-	// 	n, err = gi.file.Read(gi.indexCache)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// 	if n != int(idxSize) {
-	// 		return nil, ErrIndexCacheUnreadable
-	// 	}
-	// }
+// OpenFS opens the database with the given name in fsys, for example a database
+// embedded with embed.FS. If the file is gzip-compressed or doesn't implement
+// io.ReaderAt, it is read into memory. CheckCache is ignored
+func OpenFS(fsys fs.FS, name string, options *GeoIPOptions) (*DB, error) {
+	dbFile, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := dbFile.Stat()
+	if err != nil {
+		dbFile.Close()
+		return nil, err
+	}
 
-	// if options.MemoryCache || options.MMapCache {
-	// 	gi.ModTime = fi.ModTime()
+	r, ok := dbFile.(io.ReaderAt)
+	if ok && !isGzip(r) {
+		return openDB(r, dbFile, fi.Size(), fi.ModTime(), options)
+	}
 
-	// 	if options.MMapCache {
-	// 		gi.cache = make([]byte, gi.Size)
-	// 	}
-	// }
+	data, err := io.ReadAll(dbFile)
+	dbFile.Close()
+	if err != nil {
+		return nil, err
+	}
+	var gi *DB
+	if isGzip(bytes.NewReader(data)) {
+		gi, err = openGzip(bytes.NewReader(data), options)
+	} else {
+		gi, err = OpenBytes(data, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	gi.ModTime = fi.ModTime()
+	return gi, nil
+}
 
+// openDB sets up the database read from r, closing closer if it fails
+func openDB(r io.ReaderAt, closer io.Closer, size int64, modTime time.Time, options *GeoIPOptions) (*DB, error) {
+	gi := newDB(r, closer, size, modTime, options)
+	if err := gi.setup(); err != nil {
+		return nil, err
+	}
 	return gi, nil
 }
+
+func newDB(r io.ReaderAt, closer io.Closer, size int64, modTime time.Time, options *GeoIPOptions) *DB {
+	if options == nil {
+		options = &GeoIPOptions{}
+	}
+	return &DB{
+		reader:  r,
+		closer:  closer,
+		Size:    size,
+		ModTime: modTime,
+		Options: options,
+		Charset: Charset_ISO_8859_1,
+	}
+}
+
+// setup loads the database into memory if the options require it and it isn't
+// already, and reads its structure. The database is closed if it fails
+func (db *DB) setup() error {
+	var err error
+	options := db.Options
+	dbFile, isFile := db.reader.(*os.File)
+	if db.cache == nil && options.MMapCache && isFile {
+		if db.cache, err = mmapFile(dbFile, db.Size); err != nil {
+			db.Close()
+			return err
+		}
+		db.mmapped = true
+	} else if db.cache == nil && (options.MemoryCache || options.MMapCache) {
+		db.cache = make([]byte, db.Size)
+		if _, err = io.ReadFull(io.NewSectionReader(db.reader, 0, db.Size), db.cache); err != nil {
+			db.Close()
+			return err
+		}
+	}
+
+	if err = db.setupSegments(); err != nil {
+		db.Close()
+		return err
+	}
+	if db.segments == nil {
+		db.Close()
+		return ErrNoSegments
+	}
+
+	idxSize := db.GetIndexSize()
+	if idxSize < 0 {
+		db.Close()
+		return ErrNegativeIndex
+	}
+
+	if options.IndexCache && db.cache == nil {
+		db.indexCache = make([]byte, idxSize)
+		n, err := db.reader.ReadAt(db.indexCache, 0)
+		if n != int(idxSize) {
+			db.Close()
+			return ErrIndexCacheUnreadable
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			db.Close()
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package geoiplegacy
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fileOnlyFS hides any methods of its files other than those of fs.File, such as
+// io.ReaderAt
+type fileOnlyFS struct {
+	fs.FS
+}
+
+func (fsys fileOnlyFS) Open(name string) (fs.File, error) {
+	file, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ fs.File }{file}, nil
+}
+
+func testCityDBBuilder(t *testing.T) *testDBBuilder {
+	b := newTestDBBuilder(CityEditionRev1, false)
+	b.insert("8.8.8.0/24", b.addRecord(testCityRecord(testCountryIndex(t, "US"),
+		"CA", "Mountain View", "94043", 37.4192, -122.0574, 807650)))
+	return b
+}
+
+func assertTestCity(t *testing.T, db *DB) {
+	t.Helper()
+	if !assert.Equal(t, CityEditionRev1, db.Type) {
+		return
+	}
+	city, err := db.GetCityByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", city.Code)
+	assert.Equal(t, "Mountain View", city.City)
+	assert.Equal(t, 807, city.MetroCode)
+}
+
+func TestOpenBytes(t *testing.T) {
+	data := testCityDBBuilder(t).bytes()
+	db, err := OpenBytes(data, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assertTestCity(t, db)
+	assert.Equal(t, "", db.Path())
+	assert.Same(t, &data[0], &db.cache[0])
+	assert.NoError(t, db.Close())
+
+	_, err = OpenBytes([]byte("not a database"), nil)
+	assert.ErrorIs(t, err, ErrNoSegments)
+
+	// databases too short to contain the structure info
+	for _, data := range [][]byte{nil, {1}, {255, 255}, {255, 255, 255}} {
+		_, err = OpenBytes(data, nil)
+		assert.ErrorIs(t, err, ErrNoSegments, "%v", data)
+	}
+}
+
+func TestOpenReaderAt(t *testing.T) {
+	data := testCityDBBuilder(t).bytes()
+	db, err := OpenReaderAt(bytes.NewReader(data), int64(len(data)), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Nil(t, db.cache)
+	assertTestCity(t, db)
+	assert.NoError(t, db.Close())
+
+	db, err = OpenReaderAt(bytes.NewReader(data), int64(len(data)), &GeoIPOptions{IndexCache: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotNil(t, db.indexCache)
+	assertTestCity(t, db)
+	assert.NoError(t, db.Close())
+
+	db, err = OpenReaderAt(bytes.NewReader(data), int64(len(data)), &GeoIPOptions{MMapCache: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	// only files can be mapped, so it is read into memory instead
+	assert.False(t, db.mmapped)
+	assert.Len(t, db.cache, len(data))
+	assertTestCity(t, db)
+	assert.NoError(t, db.Close())
+}
+
+func TestOpenReaderAtLeavesFileOpenOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalid.dat")
+	if err := os.WriteFile(path, []byte{1, 2, 3, 4}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	_, err = OpenReaderAt(file, 4, nil)
+	assert.ErrorIs(t, err, ErrNoSegments)
+
+	// the caller still owns the file
+	buf := make([]byte, 4)
+	_, err = file.ReadAt(buf, 0)
+	assert.NoError(t, err)
+}
+
+func TestOpenFS(t *testing.T) {
+	modTime := time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"GeoLiteCity.dat": &fstest.MapFile{Data: testCityDBBuilder(t).bytes(), ModTime: modTime},
+	}
+
+	db, err := OpenFS(fsys, "GeoLiteCity.dat", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Nil(t, db.cache)
+	assert.Equal(t, modTime, db.ModTime)
+	assertTestCity(t, db)
+	assert.NoError(t, db.Close())
+
+	db, err = OpenFS(fileOnlyFS{fsys}, "GeoLiteCity.dat", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotNil(t, db.cache)
+	assert.Equal(t, modTime, db.ModTime)
+	assertTestCity(t, db)
+	assert.NoError(t, db.Close())
+
+	_, err = OpenFS(fsys, "GeoIP.dat", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
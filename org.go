@@ -0,0 +1,144 @@
+package geoiplegacy
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+)
+
+// nameEditions are the editions whose records are a single string
+var nameEditions = []DBType{
+	OrgEdition, OrgEditionV6,
+	ISPEdition, ISPEditionV6,
+	DomainEdition, DomainEditionV6,
+	RegistrarEdition, RegistrarEditionV6,
+	UserTypeEdition, UserTypeEditionV6,
+	ASNEdition, ASNEditionV6,
+	NetSpeedEditionRev1, NetSpeedEditionRev1V6,
+	AccuracyRadiusEdition, AccuracyRadiusEditionV6,
+	LocationAEdition, LocationAEditionV6,
+	CountryConfEdition, RegionConfEdition, CityConfEdition, PostalConfEdition,
+}
+
+// getNameByIP reads the string record of the given IP address, if the database is
+// one of the given editions
+func (db *DB) getNameByIP(ip net.IP, editions ...DBType) (string, error) {
+	name, _, err := db.getNameAndNetworkByIP(ip, editions...)
+	return name, err
+}
+
+// getNameAndNetworkByIP reads the string record of the given IP address and returns
+// it with the network containing the address, if the database is one of the given
+// editions. If the address is not in the database, the network is returned with
+// ErrRecordNotFound
+func (db *DB) getNameAndNetworkByIP(ip net.IP, editions ...DBType) (string, netip.Prefix, error) {
+	db.beginLookup()
+	defer db.endLookup()
+	if !db.isEdition(editions...) {
+		return "", netip.Prefix{}, db.invalidTypeError(editions[0])
+	}
+	seek, network, err := db.seekIP(ip)
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	name, err := db.readName(seek)
+	if errors.Is(err, ErrRecordNotFound) {
+		return "", network, err
+	}
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	return name, network, nil
+}
+
+// readName reads the string record that the seek result points to
+func (db *DB) readName(seek int) (string, error) {
+	buf, err := db.readRecord(seek, MaxOrgRecordLength)
+	if err != nil {
+		return "", err
+	}
+	name, _, err := nextString(buf)
+	if err != nil {
+		return "", err
+	}
+	return db.decodeString(name), nil
+}
+
+// getNameByAddr resolves addr if it is a domain, then reads the string record of
+// the IP address
+func (db *DB) getNameByAddr(addr string, editions ...DBType) (string, error) {
+	ips, err := net.LookupIP(addr)
+	if err != nil {
+		return "", err
+	}
+	return db.getNameByIP(ips[0], editions...)
+}
+
+// GetOrgByIP returns the string record of the given IP address. It can be used
+// with any edition that stores a single string for each network, including
+// Organization, ISP, Domain, Registrar, UserType, ASNum, Netspeed Rev 1, Accuracy Radius, LocationID
+// ASCII and Confidence editions.
+// If the address is not in the database, ErrRecordNotFound is returned
+func (db *DB) GetOrgByIP(ip net.IP) (string, error) {
+	return db.getNameByIP(ip, nameEditions...)
+}
+
+// GetOrgAndNetworkByIP returns the string record of the given IP address and the
+// network containing the address, which all have the same record. If the address
+// is not in the database, the network is returned with ErrRecordNotFound
+func (db *DB) GetOrgAndNetworkByIP(ip net.IP) (string, netip.Prefix, error) {
+	return db.getNameAndNetworkByIP(ip, nameEditions...)
+}
+
+// GetOrgByAddr returns the string record of the given IP address or domain.
+// If a domain is passed to it, it tries to resolve it to an IP, then looks that up.
+func (db *DB) GetOrgByAddr(addr string) (string, error) {
+	return db.getNameByAddr(addr, nameEditions...)
+}
+
+// GetISPByIP returns the ISP name of the given IP address from an ISP Edition database
+func (db *DB) GetISPByIP(ip net.IP) (string, error) {
+	return db.getNameByIP(ip, ISPEdition, ISPEditionV6)
+}
+
+// GetISPByAddr returns the ISP name of the given IP address or domain from an ISP
+// Edition database
+func (db *DB) GetISPByAddr(addr string) (string, error) {
+	return db.getNameByAddr(addr, ISPEdition, ISPEditionV6)
+}
+
+// GetDomainByIP returns the domain name of the given IP address from a Domain Name
+// Edition database
+func (db *DB) GetDomainByIP(ip net.IP) (string, error) {
+	return db.getNameByIP(ip, DomainEdition, DomainEditionV6)
+}
+
+// GetDomainByAddr returns the domain name of the given IP address or domain from a
+// Domain Name Edition database
+func (db *DB) GetDomainByAddr(addr string) (string, error) {
+	return db.getNameByAddr(addr, DomainEdition, DomainEditionV6)
+}
+
+// GetRegistrarByIP returns the registrar of the given IP address from a Registrar
+// Edition database
+func (db *DB) GetRegistrarByIP(ip net.IP) (string, error) {
+	return db.getNameByIP(ip, RegistrarEdition, RegistrarEditionV6)
+}
+
+// GetRegistrarByAddr returns the registrar of the given IP address or domain from a
+// Registrar Edition database
+func (db *DB) GetRegistrarByAddr(addr string) (string, error) {
+	return db.getNameByAddr(addr, RegistrarEdition, RegistrarEditionV6)
+}
+
+// GetUserTypeByIP returns the user type of the given IP address from a UserType
+// Edition database
+func (db *DB) GetUserTypeByIP(ip net.IP) (string, error) {
+	return db.getNameByIP(ip, UserTypeEdition, UserTypeEditionV6)
+}
+
+// GetUserTypeByAddr returns the user type of the given IP address or domain from a
+// UserType Edition database
+func (db *DB) GetUserTypeByAddr(addr string) (string, error) {
+	return db.getNameByAddr(addr, UserTypeEdition, UserTypeEditionV6)
+}
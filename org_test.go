@@ -0,0 +1,83 @@
+package geoiplegacy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestISPByIPv4(t *testing.T) {
+	b := newTestDBBuilder(ISPEdition, false)
+	b.insert("8.8.8.0/24", b.addString("Google LLC"))
+	b.insert("81.91.160.0/20", b.addString("Deutsche Telekom AG"))
+	db := b.open(t)
+
+	if !assert.Equal(t, ISPEdition, db.Type) || !assert.EqualValues(t, OrgRecordLength, db.RecordLength) {
+		return
+	}
+
+	isp, err := db.GetISPByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Google LLC", isp)
+
+	isp, err = db.GetOrgByIP(net.ParseIP("81.91.170.12"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Deutsche Telekom AG", isp)
+
+	_, err = db.GetISPByAddr("10.0.0.1")
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = db.GetDomainByAddr("8.8.8.8")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
+
+func TestOrgByIPv6(t *testing.T) {
+	b := newTestDBBuilder(OrgEditionV6, true)
+	b.insert("2a0b:5f80::/29", b.addString("Soci\xe9t\xe9 G\xe9n\xe9rale"))
+	db := b.open(t)
+
+	org, err := db.GetOrgByAddr("2a0b:5f80::1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Soci\xe9t\xe9 G\xe9n\xe9rale", org)
+
+	db.Charset = Charset_UTF_8
+	org, err = db.GetOrgByAddr("2a0b:5f80::1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Société Générale", org)
+}
+
+func TestStringEditions(t *testing.T) {
+	tests := []struct {
+		dbType DBType
+		lookup func(db *DB, addr string) (string, error)
+	}{
+		{DomainEdition, (*DB).GetDomainByAddr},
+		{RegistrarEdition, (*DB).GetRegistrarByAddr},
+		{UserTypeEdition, (*DB).GetUserTypeByAddr},
+	}
+	for _, tc := range tests {
+		t.Run(tc.dbType.String(), func(t *testing.T) {
+			b := newTestDBBuilder(tc.dbType, false)
+			b.insert("8.8.8.0/24", b.addString("value"))
+			db := b.open(t)
+
+			val, err := tc.lookup(db, "8.8.8.8")
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "value", val)
+
+			_, err = db.GetISPByAddr("8.8.8.8")
+			assert.ErrorIs(t, err, ErrInvalidDBType)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package geoiplegacy
+
+import (
+	"net"
+)
+
+func (pt ProxyType) String() string {
+	switch pt {
+	case NoProxy:
+		return "Not a proxy"
+	case AnonProxy:
+		return "Anonymous proxy"
+	case HTTPXForwardedForProxy:
+		return "HTTP X-Forwarded-For proxy"
+	}
+	return "Unknown"
+}
+
+// GetProxyTypeByIP scans a Proxy Edition database for the type of proxy at the
+// given IP address. NoProxy is returned if it isn't a known proxy
+func (db *DB) GetProxyTypeByIP(ip net.IP) (ProxyType, error) {
+	id, err := db.getIDByIP(ip, ProxyEdition)
+	if err != nil {
+		return NoProxy, err
+	}
+	return ProxyType(id), nil
+}
+
+// GetProxyTypeByAddr scans a Proxy Edition database for the given IP address or domain.
+// If a domain is passed to it, it tries to resolve it to an IP, then looks that up.
+func (db *DB) GetProxyTypeByAddr(addr string) (ProxyType, error) {
+	ips, err := net.LookupIP(addr)
+	if err != nil {
+		return NoProxy, err
+	}
+	return db.GetProxyTypeByIP(ips[0])
+}
@@ -0,0 +1,39 @@
+package geoiplegacy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyType(t *testing.T) {
+	b := newTestDBBuilder(ProxyEdition, false)
+	b.insert("1.2.3.0/24", uint(AnonProxy))
+	b.insert("5.6.7.8/32", uint(HTTPXForwardedForProxy))
+	db := b.open(t)
+
+	proxyType, err := db.GetProxyTypeByAddr("1.2.3.4")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, AnonProxy, proxyType)
+	assert.Equal(t, "Anonymous proxy", proxyType.String())
+
+	proxyType, err = db.GetProxyTypeByAddr("5.6.7.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, HTTPXForwardedForProxy, proxyType)
+
+	proxyType, err = db.GetProxyTypeByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, NoProxy, proxyType)
+
+	_, err = db.GetCountryByAddr("1.2.3.4")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+
+	_, err = db.GetNetSpeedByAddr("1.2.3.4")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
@@ -0,0 +1,103 @@
+package geoiplegacy
+
+import (
+	"net"
+	"net/netip"
+)
+
+// RegionResult is the result of scanning a Region Edition database for the
+// location of a network address. Region is the two letter state or province
+// code for US and Canadian addresses, and empty for other countries, since the
+// database only records the country for them
+type RegionResult struct {
+	CountryResult
+	Region string
+}
+
+func (db *DB) isRegionEdition() bool {
+	return db.Type == RegionEditionRev0 || db.Type == RegionEditionRev1
+}
+
+// regionCode returns the two letter code for the region offset
+func regionCode(offset uint) string {
+	return string([]byte{byte(offset/26 + 65), byte(offset%26 + 65)})
+}
+
+// getRegionBySeek translates the seek result into a country and region
+func (db *DB) getRegionBySeek(seek uint) (*RegionResult, error) {
+	var countryID int
+	var region string
+	if db.Type == RegionEditionRev0 {
+		// Region Edition, pre June 2003
+		seek -= StateBeginRev0
+		if seek >= 1000 {
+			countryID = usCountryID
+			region = regionCode(seek - 1000)
+		} else {
+			countryID = int(seek)
+		}
+	} else {
+		// Region Edition, post June 2003
+		seek -= StateBeginRev1
+		if seek < USOffset {
+			// unknown
+			countryID = 0
+		} else if seek < CanadaOffset {
+			// USA state
+			countryID = usCountryID
+			region = regionCode(seek - USOffset)
+		} else if seek < WorldOffset {
+			// Canada province
+			countryID = canadaCountryID
+			region = regionCode(seek - CanadaOffset)
+		} else {
+			// not US or Canada, the seek is always WorldOffset + country ID * FIPSRange
+			countryID = int((seek - WorldOffset) / FIPSRange)
+		}
+	}
+	country, err := countryByIndex(countryID)
+	if err != nil {
+		return nil, err
+	}
+	return &RegionResult{
+		CountryResult: *country,
+		Region:        region,
+	}, nil
+}
+
+// GetRegionByIP scans a Region Edition database for the country and region of
+// the given IP address
+func (db *DB) GetRegionByIP(ip net.IP) (*RegionResult, error) {
+	region, _, err := db.GetRegionAndNetworkByIP(ip)
+	return region, err
+}
+
+// GetRegionAndNetworkByIP scans a Region Edition database for the country and
+// region of the given IP address, returning them and the network containing the
+// address, which all have the same country and region
+func (db *DB) GetRegionAndNetworkByIP(ip net.IP) (*RegionResult, netip.Prefix, error) {
+	db.beginLookup()
+	defer db.endLookup()
+	if !db.isRegionEdition() {
+		return nil, netip.Prefix{}, db.invalidTypeError(RegionEditionRev1)
+	}
+	seek, network, err := db.seekIP(ip)
+	if err != nil {
+		return nil, netip.Prefix{}, err
+	}
+	region, err := db.getRegionBySeek(uint(seek))
+	if err != nil {
+		return nil, netip.Prefix{}, err
+	}
+	return region, network, nil
+}
+
+// GetRegionByAddr scans a Region Edition database for the given IP address or domain.
+// If a domain is passed to it, it tries to resolve it to an IP, then looks that up.
+func (db *DB) GetRegionByAddr(addr string) (*RegionResult, error) {
+	ips, err := net.LookupIP(addr)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetRegionByIP(ips[0])
+}
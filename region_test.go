@@ -0,0 +1,84 @@
+package geoiplegacy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRegionOffset returns the offset of the two letter region code from the
+// first region of its country
+func testRegionOffset(region string) uint {
+	return uint(region[0]-65)*26 + uint(region[1]-65)
+}
+
+func TestRegionRev1(t *testing.T) {
+	b := newTestDBBuilder(RegionEditionRev1, false)
+	b.insert("8.8.8.0/24", USOffset+testRegionOffset("CA"))
+	b.insert("24.48.0.0/16", CanadaOffset+testRegionOffset("QC"))
+	b.insert("81.91.0.0/16", WorldOffset+uint(testCountryIndex(t, "DE"))*FIPSRange)
+	db := b.open(t)
+
+	if !assert.Equal(t, RegionEditionRev1, db.Type) {
+		return
+	}
+
+	region, err := db.GetRegionByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", region.Code)
+	assert.Equal(t, "CA", region.Region)
+
+	region, err = db.GetRegionByIP(net.ParseIP("24.48.1.1"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "CA", region.Code)
+	assert.Equal(t, "Canada", region.NameASCII)
+	assert.Equal(t, "QC", region.Region)
+
+	region, err = db.GetRegionByAddr("81.91.170.12")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "DE", region.Code)
+	assert.Equal(t, "", region.Region)
+
+	region, err = db.GetRegionByAddr("10.0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "--", region.Code)
+	assert.Equal(t, "", region.Region)
+}
+
+func TestRegionRev0(t *testing.T) {
+	b := newTestDBBuilder(RegionEditionRev0, false)
+	b.insert("8.8.8.0/24", 1000+testRegionOffset("NY"))
+	b.insert("81.91.0.0/16", uint(testCountryIndex(t, "DE")))
+	db := b.open(t)
+
+	region, err := db.GetRegionByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", region.Code)
+	assert.Equal(t, "NY", region.Region)
+
+	region, err = db.GetRegionByAddr("81.91.170.12")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "DE", region.Code)
+	assert.Equal(t, "", region.Region)
+}
+
+func TestRegionInvalidDBType(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	db := b.open(t)
+
+	_, err := db.GetRegionByAddr("8.8.8.8")
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
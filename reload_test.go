@@ -0,0 +1,239 @@
+package geoiplegacy
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// replaceTestDB atomically replaces the database at path, the way geoipupdate
+// does, and sets its modification time
+func replaceTestDB(t *testing.T, path string, b *testDBBuilder, modTime time.Time) {
+	t.Helper()
+	tmpPath := filepath.Join(filepath.Dir(path), "new.dat")
+	if err := os.WriteFile(tmpPath, b.bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tmpPath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckCacheReload(t *testing.T) {
+	tests := []struct {
+		name    string
+		options GeoIPOptions
+	}{
+		{"file", GeoIPOptions{CheckCache: true}},
+		{"MemoryCache", GeoIPOptions{CheckCache: true, MemoryCache: true}},
+		{"MMapCache", GeoIPOptions{CheckCache: true, MMapCache: true}},
+		{"IndexCache", GeoIPOptions{CheckCache: true, IndexCache: true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.options.MMapCache && runtime.GOOS != "linux" {
+				t.Skip("memory mapped databases are only supported on Linux")
+			}
+			oldDB := newTestDBBuilder(CountryEdition, false)
+			oldDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+			path := oldDB.write(t)
+
+			db, err := OpenDB(path, &tc.options)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer func() {
+				assert.NoError(t, db.Close())
+			}()
+
+			country, err := db.GetCountryByAddr("8.8.8.8")
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "US", country.Code)
+
+			newDB := newTestDBBuilder(CityEditionRev1, false)
+			newDB.insert("8.8.8.0/24", newDB.addRecord(testCityRecord(testCountryIndex(t, "DE"),
+				"07", "Koln", "", 50.9333, 6.95, 0)))
+
+			// a database modified less than a minute ago may still be being written
+			replaceTestDB(t, path, newDB, time.Now())
+			db.lastModTimeCheck.Store(0)
+			country, err = db.GetCountryByAddr("8.8.8.8")
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "US", country.Code)
+			assert.Equal(t, CountryEdition, db.Type)
+
+			modTime := time.Now().Add(-2 * time.Minute).Truncate(time.Second)
+			replaceTestDB(t, path, newDB, modTime)
+			db.lastModTimeCheck.Store(0)
+			city, err := db.GetCityByAddr("8.8.8.8")
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "DE", city.Code)
+			assert.Equal(t, "Koln", city.City)
+			assert.Equal(t, CityEditionRev1, db.Type)
+			assert.True(t, modTime.Equal(db.ModTime))
+			assert.EqualValues(t, len(newDB.bytes()), db.Size)
+
+			_, err = db.GetCountryByAddr("8.8.8.8")
+			assert.ErrorIs(t, err, ErrInvalidDBType)
+		})
+	}
+}
+
+func TestCheckCacheDisabled(t *testing.T) {
+	oldDB := newTestDBBuilder(CountryEdition, false)
+	oldDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	db := oldDB.open(t)
+
+	newDB := newTestDBBuilder(CountryEdition, false)
+	newDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "DE")))
+	replaceTestDB(t, db.Path(), newDB, time.Now().Add(-2*time.Minute))
+
+	country, err := db.GetCountryByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+}
+
+func TestCheckCacheCorruptReplacement(t *testing.T) {
+	oldDB := newTestDBBuilder(CountryEdition, false)
+	oldDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	db := oldDB.openWithOptions(t, &GeoIPOptions{CheckCache: true, MemoryCache: true})
+
+	// a half-written file without the structure info
+	corrupt := filepath.Join(filepath.Dir(db.Path()), "corrupt.dat")
+	if err := os.WriteFile(corrupt, []byte{1, 2, 3, 4}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-2 * time.Minute).Truncate(time.Second)
+	if err := os.Chtimes(corrupt, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(corrupt, db.Path()); err != nil {
+		t.Fatal(err)
+	}
+
+	db.lastModTimeCheck.Store(0)
+	country, err := db.GetCountryByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+	assert.ErrorIs(t, db.LastReloadError(), ErrNoSegments)
+
+	// the same file isn't reloaded again until it is modified
+	db.setReloadError(nil)
+	db.lastModTimeCheck.Store(0)
+	country, err = db.GetCountryByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+	assert.NoError(t, db.LastReloadError())
+
+	newDB := newTestDBBuilder(CountryEdition, false)
+	newDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "DE")))
+	replaceTestDB(t, db.Path(), newDB, modTime.Add(time.Second))
+	db.setReloadError(ErrNoSegments)
+	db.lastModTimeCheck.Store(0)
+	country, err = db.GetCountryByAddr("8.8.8.8")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "DE", country.Code)
+	assert.NoError(t, db.LastReloadError())
+}
+
+func TestCheckCacheAfterClose(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("memory mapped databases are only supported on Linux")
+	}
+	oldDB := newTestDBBuilder(CountryEdition, false)
+	oldDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	db, err := OpenDB(oldDB.write(t), &GeoIPOptions{CheckCache: true, MMapCache: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.NoError(t, db.Close()) {
+		return
+	}
+
+	newDB := newTestDBBuilder(CountryEdition, false)
+	newDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "DE")))
+	replaceTestDB(t, db.Path(), newDB, time.Now().Add(-2*time.Minute))
+
+	// a lookup after Close doesn't reopen the database
+	db.lastModTimeCheck.Store(0)
+	_, err = db.GetCountryByIP(net.ParseIP("8.8.8.8"))
+	assert.Error(t, err)
+	assert.False(t, db.mmapped)
+	assert.Nil(t, db.closer)
+
+	// a reload that finishes after Close discards the new database
+	assert.NoError(t, db.reload())
+	assert.False(t, db.mmapped)
+	assert.Nil(t, db.closer)
+	assert.Nil(t, db.cache)
+}
+
+func TestCheckCacheSingleReload(t *testing.T) {
+	oldDB := newTestDBBuilder(CountryEdition, false)
+	oldDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	db := oldDB.openWithOptions(t, &GeoIPOptions{CheckCache: true, MemoryCache: true})
+
+	newDB := newTestDBBuilder(CountryEdition, false)
+	newDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "DE")))
+	replaceTestDB(t, db.Path(), newDB, time.Now().Add(-2*time.Minute))
+
+	// while another reload is running, lookups don't start a second one
+	db.reloading.Store(true)
+	db.lastModTimeCheck.Store(0)
+	country, err := db.GetCountryByIP(net.ParseIP("8.8.8.8"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "US", country.Code)
+
+	db.reloading.Store(false)
+	db.lastModTimeCheck.Store(0)
+	country, err = db.GetCountryByIP(net.ParseIP("8.8.8.8"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "DE", country.Code)
+	assert.False(t, db.reloading.Load())
+}
+
+func TestEditionDuringReload(t *testing.T) {
+	oldDB := newTestDBBuilder(CountryEdition, false)
+	oldDB.insert("8.8.8.0/24", uint(testCountryIndex(t, "US")))
+	db := oldDB.openWithOptions(t, &GeoIPOptions{CheckCache: true, MemoryCache: true})
+
+	newDB := newTestDBBuilder(CityEditionRev1, false)
+	replaceTestDB(t, db.Path(), newDB, time.Now().Add(-2*time.Minute))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		db.lastModTimeCheck.Store(0)
+		db.GetCountryByIP(net.ParseIP("8.8.8.8"))
+	}()
+	edition := db.Edition()
+	assert.True(t, edition == CountryEdition || edition == CityEditionRev1)
+	<-done
+	assert.Equal(t, CityEditionRev1, db.Edition())
+}
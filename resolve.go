@@ -0,0 +1,74 @@
+package geoiplegacy
+
+import (
+	"context"
+	"net"
+	"net/netip"
+)
+
+// Resolver looks up the IP addresses of a host. It is implemented by
+// *net.Resolver, and can be replaced to control how hosts are resolved
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// AddrCountry is the country of one of the addresses that a host resolves to
+type AddrCountry struct {
+	Addr    netip.Addr
+	Country *CountryResult
+}
+
+func resolverOrDefault(resolver Resolver) Resolver {
+	if resolver == nil {
+		return net.DefaultResolver
+	}
+	return resolver
+}
+
+// lookupHostCountries resolves the host using the given network ("ip", "ip4" or
+// "ip6") and looks up the country of each address
+func lookupHostCountries(ctx context.Context, resolver Resolver, network, host string,
+	lookup func(netip.Addr) (*CountryResult, error)) ([]AddrCountry, error) {
+	addrs, err := resolverOrDefault(resolver).LookupNetIP(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]AddrCountry, 0, len(addrs))
+	for _, addr := range addrs {
+		country, err := lookup(addr.Unmap())
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, AddrCountry{Addr: addr, Country: country})
+	}
+	return results, nil
+}
+
+// GetCountryByHostContext resolves the host using db.Resolver, or the default
+// resolver if it is nil, and scans the database for the country of every address
+// that it resolves to and the database can look up: only IPv4 addresses for IPv4
+// editions, and both IPv4 and IPv6 addresses for IPv6 editions. The context is
+// used for the resolver's deadline and cancellation
+func (db *DB) GetCountryByHostContext(ctx context.Context, host string) ([]AddrCountry, error) {
+	network := "ip4"
+	switch db.Edition() {
+	case CountryEditionV6, LargeCountryEditionV6:
+		network = "ip"
+	}
+	return lookupHostCountries(ctx, db.Resolver, network, host, db.GetCountryByNetIP)
+}
+
+// GetCountryByHostContext resolves the host using db.Resolver, or the default
+// resolver if it is nil, and scans the databases for the country of every address
+// it resolves to. If only one of the databases is open, only addresses of its
+// family are looked up. The context is used for the resolver's deadline and
+// cancellation
+func (db *CombinedDB) GetCountryByHostContext(ctx context.Context, host string) ([]AddrCountry, error) {
+	network := "ip"
+	if db.v4DB == nil && db.v6DB != nil {
+		network = "ip6"
+	} else if db.v6DB == nil && db.v4DB != nil {
+		network = "ip4"
+	}
+	return lookupHostCountries(ctx, db.Resolver, network, host, db.GetCountryByNetIP)
+}
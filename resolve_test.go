@@ -0,0 +1,117 @@
+package geoiplegacy
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testResolver resolves hosts from a map instead of using DNS
+type testResolver map[string][]netip.Addr
+
+func (r testResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var addrs []netip.Addr
+	for _, addr := range r[host] {
+		if network == "ip" || (network == "ip4") == addr.Is4() {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, &testDNSError{host}
+	}
+	return addrs, nil
+}
+
+type testDNSError struct {
+	host string
+}
+
+func (e *testDNSError) Error() string {
+	return "no such host " + e.host
+}
+
+var googleResolver = testResolver{
+	"google.com": {
+		netip.MustParseAddr("142.250.72.14"),
+		netip.MustParseAddr("8.8.8.8"),
+		netip.MustParseAddr("2607:f8b0:4005:80c::200e"),
+	},
+}
+
+func TestCountryByHostContext(t *testing.T) {
+	b := newTestDBBuilder(CountryEdition, false)
+	b.insert("142.250.0.0/15", uint(testCountryIndex(t, "US")))
+	b.insert("8.8.8.0/24", uint(testCountryIndex(t, "CA")))
+	db := b.open(t)
+	db.Resolver = googleResolver
+
+	results, err := db.GetCountryByHostContext(context.Background(), "google.com")
+	if !assert.NoError(t, err) || !assert.Len(t, results, 2) {
+		return
+	}
+	assert.Equal(t, netip.MustParseAddr("142.250.72.14"), results[0].Addr)
+	assert.Equal(t, "US", results[0].Country.Code)
+	assert.Equal(t, netip.MustParseAddr("8.8.8.8"), results[1].Addr)
+	assert.Equal(t, "CA", results[1].Country.Code)
+
+	var dnsErr *testDNSError
+	_, err = db.GetCountryByHostContext(context.Background(), "example.invalid")
+	assert.ErrorAs(t, err, &dnsErr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	_, err = db.GetCountryByHostContext(ctx, "google.com")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestV6CountryByHostContext(t *testing.T) {
+	b := newTestDBBuilder(CountryEditionV6, true)
+	b.insert("::142.250.0.0/111", uint(testCountryIndex(t, "US")))
+	b.insert("2607:f8b0::/32", uint(testCountryIndex(t, "US")))
+	db := b.open(t)
+	db.Resolver = googleResolver
+
+	// IPv6 editions also look up IPv4 addresses
+	results, err := db.GetCountryByHostContext(context.Background(), "google.com")
+	if !assert.NoError(t, err) || !assert.Len(t, results, 3) {
+		return
+	}
+	assert.Equal(t, netip.MustParseAddr("142.250.72.14"), results[0].Addr)
+	assert.Equal(t, "US", results[0].Country.Code)
+	assert.Equal(t, netip.MustParseAddr("8.8.8.8"), results[1].Addr)
+	assert.Equal(t, "--", results[1].Country.Code)
+	assert.Equal(t, netip.MustParseAddr("2607:f8b0:4005:80c::200e"), results[2].Addr)
+	assert.Equal(t, "US", results[2].Country.Code)
+}
+
+func TestCombinedCountryByHostContext(t *testing.T) {
+	v4 := newTestDBBuilder(CountryEdition, false)
+	v4.insert("142.250.0.0/15", uint(testCountryIndex(t, "US")))
+	v6 := newTestDBBuilder(CountryEditionV6, true)
+	v6.insert("2607:f8b0::/32", uint(testCountryIndex(t, "US")))
+
+	db, err := OpenCombinedDB(v4.write(t), v6.write(t))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		assert.NoError(t, db.Close())
+	}()
+	db.Resolver = googleResolver
+
+	results, err := db.GetCountryByHostContext(context.Background(), "google.com")
+	if !assert.NoError(t, err) || !assert.Len(t, results, 3) {
+		return
+	}
+	assert.Equal(t, "US", results[0].Country.Code)
+	assert.Equal(t, "--", results[1].Country.Code)
+	assert.Equal(t, netip.MustParseAddr("2607:f8b0:4005:80c::200e"), results[2].Addr)
+	assert.Equal(t, "US", results[2].Country.Code)
+}
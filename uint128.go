@@ -0,0 +1,135 @@
+package geoiplegacy
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"net"
+	"net/netip"
+	"strconv"
+)
+
+// uint128 is an IPv6 address as a 128-bit unsigned integer, so that IPv6 lookups
+// and network ranges can be computed without allocating
+type uint128 struct {
+	hi uint64
+	lo uint64
+}
+
+// uint128FromIP returns the number of a 16 byte IP address
+func uint128FromIP(ip net.IP) uint128 {
+	return uint128{
+		hi: binary.BigEndian.Uint64(ip[:8]),
+		lo: binary.BigEndian.Uint64(ip[8:]),
+	}
+}
+
+// uint128FromAddr returns the number of an address, with IPv4 addresses mapped
+// to IPv6
+func uint128FromAddr(addr netip.Addr) uint128 {
+	b := addr.As16()
+	return uint128FromIP(b[:])
+}
+
+// addr returns the IPv6 address of the number
+func (u uint128) addr() netip.Addr {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], u.hi)
+	binary.BigEndian.PutUint64(b[8:], u.lo)
+	return netip.AddrFrom16(b)
+}
+
+// bit returns 1 if the bit at position i is set, counting from the least
+// significant bit, or 0 otherwise
+func (u uint128) bit(i int) uint64 {
+	if i >= 64 {
+		return (u.hi >> (i - 64)) & 1
+	}
+	return (u.lo >> i) & 1
+}
+
+// hostMask returns a number with the lowest 128-prefixLen bits set, which are
+// the host bits of a network with the given prefix length
+func hostMask(prefixLen int) uint128 {
+	switch {
+	case prefixLen <= 0:
+		return uint128{^uint64(0), ^uint64(0)}
+	case prefixLen < 64:
+		return uint128{^uint64(0) >> prefixLen, ^uint64(0)}
+	case prefixLen < 128:
+		return uint128{0, ^uint64(0) >> (prefixLen - 64)}
+	}
+	return uint128{}
+}
+
+// prefixRange returns the first and last addresses of a network as numbers.
+// IPv4 networks are returned as their IPv4-mapped IPv6 range
+func prefixRange(prefix netip.Prefix) (uint128, uint128) {
+	bitLen := prefix.Bits()
+	if prefix.Addr().Is4() {
+		bitLen += 96
+	}
+	mask := hostMask(bitLen)
+	num := uint128FromAddr(prefix.Addr())
+	first := uint128{num.hi &^ mask.hi, num.lo &^ mask.lo}
+	last := uint128{num.hi | mask.hi, num.lo | mask.lo}
+	return first, last
+}
+
+// add1 returns u+1, wrapping around to 0 after the largest number
+func (u uint128) add1() uint128 {
+	lo, carry := bits.Add64(u.lo, 1, 0)
+	return uint128{u.hi + carry, lo}
+}
+
+// teredo returns the IPv4 address (as an IPv4-compatible IPv6 number) that a
+// Teredo address (2001:0::/32) maps to, or u itself if it isn't one
+func (u uint128) teredo() uint128 {
+	if u.hi>>32 != 0x20010000 {
+		return u
+	}
+	return uint128{0, ^u.lo & 0xffffffff}
+}
+
+// String returns the number in decimal
+func (u uint128) String() string {
+	if u.hi == 0 {
+		return strconv.FormatUint(u.lo, 10)
+	}
+	// split the number into 19 digit parts, the most that fit in a uint64
+	const div = 1e19
+	var buf [40]byte
+	i := len(buf)
+	for u.hi != 0 {
+		var rem uint64
+		u.hi, rem = bits.Div64(0, u.hi, div)
+		u.lo, rem = bits.Div64(rem, u.lo, div)
+		for j := 0; j < 19; j++ {
+			i--
+			buf[i] = byte('0' + rem%10)
+			rem /= 10
+		}
+	}
+	return strconv.FormatUint(u.lo, 10) + string(buf[i:])
+}
+
+// setBit returns u with the bit at position i set, counting from the least
+// significant bit
+func (u uint128) setBit(i int) uint128 {
+	if i >= 64 {
+		u.hi |= 1 << (i - 64)
+	} else {
+		u.lo |= 1 << i
+	}
+	return u
+}
+
+// prefix returns the network with the given prefix length starting at u. If
+// bitLen is 32, u is an IPv4 address in its lowest 32 bits
+func (u uint128) prefix(prefixLen int, bitLen int) netip.Prefix {
+	if bitLen == 32 {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(u.lo))
+		return netip.PrefixFrom(netip.AddrFrom4(b), prefixLen)
+	}
+	return netip.PrefixFrom(u.addr(), prefixLen)
+}
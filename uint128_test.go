@@ -0,0 +1,76 @@
+package geoiplegacy
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUint128String(t *testing.T) {
+	for _, addr := range []string{
+		"::", "::1", "::ffff:ffff:ffff:ffff", "::1:0:0:0:0", "2001:4860:4860::8888",
+		"2801::", "8000::1", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+	} {
+		a := netip.MustParseAddr(addr)
+		expected := new(big.Int).SetBytes(a.AsSlice()).String()
+		num := uint128FromAddr(a)
+		assert.Equal(t, expected, num.String(), addr)
+		assert.Equal(t, a, num.addr(), addr)
+	}
+}
+
+func TestUint128Bit(t *testing.T) {
+	num := uint128FromIP(net.ParseIP("8000::1"))
+	assert.EqualValues(t, 1, num.bit(127))
+	assert.EqualValues(t, 0, num.bit(126))
+	assert.EqualValues(t, 0, num.bit(64))
+	assert.EqualValues(t, 0, num.bit(63))
+	assert.EqualValues(t, 1, num.bit(0))
+}
+
+func TestPrefixRange(t *testing.T) {
+	tests := []struct {
+		prefix string
+		first  string
+		last   string
+	}{
+		{"2801::/16", "2801::", "2801:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+		{"2001:4860::/32", "2001:4860::", "2001:4860:ffff:ffff:ffff:ffff:ffff:ffff"},
+		{"2001:db8::/96", "2001:db8::", "2001:db8::ffff:ffff"},
+		{"::/0", "::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+		{"::1/128", "::1", "::1"},
+		{"8.8.8.0/24", "::ffff:8.8.8.0", "::ffff:8.8.8.255"},
+	}
+	for _, tc := range tests {
+		first, last := prefixRange(netip.MustParsePrefix(tc.prefix))
+		assert.Equal(t, netip.MustParseAddr(tc.first), first.addr(), tc.prefix)
+		assert.Equal(t, netip.MustParseAddr(tc.last), last.addr(), tc.prefix)
+	}
+
+	_, last := prefixRange(netip.MustParsePrefix("2001:db8::/64"))
+	assert.Equal(t, netip.MustParseAddr("2001:db8:0:1::"), last.add1().addr())
+}
+
+func TestUint128Teredo(t *testing.T) {
+	num := uint128FromIP(net.ParseIP("2001:0:4136:e378:8000:63bf:f7f7:f7f7"))
+	assert.Equal(t, netip.MustParseAddr("::8.8.8.8"), num.teredo().addr())
+
+	num = uint128FromIP(net.ParseIP("2801::1"))
+	assert.Equal(t, num, num.teredo())
+}
+
+func TestCorruptIPv6Tree(t *testing.T) {
+	b := newTestDBBuilder(CountryEditionV6, true)
+	// the root node points back to itself, so the lookup never reaches a leaf
+	b.nodes[0] = [2]testNodeChild{{isNode: true}, {isNode: true}}
+	db := b.open(t)
+
+	_, err := db.GetCountryByIP(net.ParseIP("2801::1"))
+	if !assert.Error(t, err) {
+		return
+	}
+	assert.Contains(t, err.Error(), "ipNum = 53174312128255169743780812907543003137")
+}
@@ -1,10 +1,12 @@
 package geoiplegacy
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
-	"math/big"
+	"fmt"
 	"net"
+	"net/netip"
 )
 
 var (
@@ -12,49 +14,55 @@ var (
 	ErrInvalidCountryID     = errors.New("invalid country id")
 	ErrInvalidIP            = errors.New("invalid IP address")
 	ErrNotIPv6              = errors.New("expected IPv6, got IPv4")
+	ErrNotIPv4              = errors.New("expected IPv4, got IPv6")
 	ErrNegativeIndex        = errors.New("index size is negative, database may be corrupt")
 	ErrIndexCacheUnreadable = errors.New("unable to read into index cache")
 	ErrSegmentNotRead       = errors.New("didn't read full segment")
+	ErrInvalidDBType        = errors.New("invalid database type")
+	ErrRecordNotFound       = errors.New("no record found for address")
+	ErrInvalidRecord        = errors.New("database record is truncated or malformed")
+	ErrMMapUnsupported      = errors.New("memory mapped databases are not supported on this platform")
 )
 
-func checkBitV6(bit uint8, data []byte) byte {
-	return (data[((127-bit)>>3)] & (1 << (^(127 - bit) & 7)))
-}
-
 // ipv4ToNumber returns a 32-bit unsigned integer  representing the IPv4 address
 func ipv4ToNumber(addr net.IP) uint32 {
 	return binary.BigEndian.Uint32(addr.To4())
 }
 
-// ipv6ToNumber returns a big int from the bytes of the IP. It assumes it is
-// IPv6, though it does not check
-func ipv6ToNumber(addr net.IP) *big.Int {
-	num := big.NewInt(0)
-	num.SetBytes(addr)
-	return num
+// parseAddr parses an IPv4 or IPv6 address literal, returning an error wrapping
+// ErrInvalidIP if it isn't one
+func parseAddr(addr string) (netip.Addr, error) {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%w %q", ErrInvalidIP, addr)
+	}
+	return ip, nil
 }
 
-func prepareTeredo(ip net.IP) {
-	if len(ip) != net.IPv6len {
-		return
+// nextString returns the NUL-terminated string at the start of buf and the
+// remainder of buf following the terminator
+func nextString(buf []byte) ([]byte, []byte, error) {
+	end := bytes.IndexByte(buf, 0)
+	if end < 0 {
+		return nil, nil, ErrInvalidRecord
 	}
-	var i int
-	if ip[0] != 0x20 &&
-		ip[1] != 0x01 &&
-		ip[2] != 0x00 &&
-		ip[3] != 0x00 {
-		return
-	}
-	for i = 0; i < 12; i++ {
-		ip[i] = 0
+	return buf[:end], buf[end+1:], nil
+}
+
+// decodeString converts a string read from the database to the database's
+// configured charset. Strings are stored as ISO-8859-1
+func (db *DB) decodeString(str []byte) string {
+	if db.Charset != Charset_UTF_8 {
+		return string(str)
 	}
-	for ; i < 16; i++ {
-		ip[i] ^= 0xff
+	runes := make([]rune, len(str))
+	for i, c := range str {
+		runes[i] = rune(c)
 	}
+	return string(runes)
 }
 
-func (db *DB) setupBuffers() ([]uint8, []byte) {
-	stackBuffer := make([]uint8, MaxRecordLength*2)
-	var buf []byte = stackBuffer
-	return stackBuffer, buf
+// readUint24 returns the little-endian 3 byte integer at the start of buf
+func readUint24(buf []byte) uint {
+	return uint(buf[0]) | uint(buf[1])<<8 | uint(buf[2])<<16
 }